@@ -5,13 +5,16 @@ package main
 import (
 	"fmt"
 	"io"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 
 	"github.com/sverrirab/wsl-host-start/internal/allowlist"
 	"github.com/sverrirab/wsl-host-start/internal/config"
 	"github.com/sverrirab/wsl-host-start/internal/drives"
+	"github.com/sverrirab/wsl-host-start/internal/envpolicy"
 	"github.com/sverrirab/wsl-host-start/internal/protocol"
 	"github.com/sverrirab/wsl-host-start/internal/signing"
 )
@@ -60,17 +63,26 @@ func printHostCheckConfig(w io.Writer, dir string, verbose bool) {
 		} else {
 			fmt.Fprintf(w, "Status:    ACTIVE (%d rules)\n", len(al.List.Allow))
 			for _, rule := range al.List.Allow {
-				if len(rule.Commands) == 0 {
-					fmt.Fprintf(w, "  allow:   %s (any args)\n", rule.Program)
-				} else {
-					fmt.Fprintf(w, "  allow:   %s [%s]\n", rule.Program, strings.Join(rule.Commands, ", "))
-				}
+				fmt.Fprintf(w, "  allow:   %s\n", rule)
 				// Warn if this rule targets a denied program.
-				if allowlist.CheckDenyList(rule.Program) != nil {
+				prog := rule.Program
+				if prog == "" {
+					prog = rule.Path
+				}
+				if allowlist.CheckDenyList(prog) != nil {
 					fmt.Fprintf(w, "           ^ WARNING: this program is on the deny list and will always be blocked\n")
 				}
 			}
 		}
+		if al.Loaded {
+			schemes := "(none — all URIs DENIED)"
+			if len(al.List.Schemes) > 0 {
+				schemes = strings.Join(al.List.Schemes, ", ")
+			}
+			fmt.Fprintf(w, "Schemes:   %s\n", schemes)
+			fmt.Fprintf(w, "Verbs:     %s (rules without their own verbs)\n", strings.Join(al.List.Policy.DefaultVerbs(), ", "))
+			fmt.Fprintf(w, "Elevation: %s\n", al.List.Policy.ElevationSummary())
+		}
 	}
 
 	// Config signing
@@ -113,6 +125,14 @@ func printHostCheckConfig(w io.Writer, dir string, verbose bool) {
 		} else {
 			fmt.Fprintf(w, "Block:     %s\n", strings.Join(cfg.Env.Block, ", "))
 		}
+		fmt.Fprintf(w, "Protected: %s (never forwarded)\n", strings.Join(envpolicy.ProtectedNames(), ", "))
+		for _, name := range slices.Sorted(maps.Keys(cfg.Env.Programs)) {
+			prog := strings.TrimSuffix(strings.ToLower(name), ".exe")
+			env := cfg.Env.ForProgram(prog)
+			fmt.Fprintf(w, "Program:   %s\n", prog)
+			fmt.Fprintf(w, "  Forward: %s\n", listOrNone(env.Forward))
+			fmt.Fprintf(w, "  Block:   %s\n", listOrNone(env.Block))
+		}
 	}
 
 	// Drives
@@ -171,3 +191,11 @@ func printHostCheckConfig(w io.Writer, dir string, verbose bool) {
 		fmt.Fprintf(w, "Show: %s\n", cfg.Defaults.Show)
 	}
 }
+
+// listOrNone joins entries, or returns "(none)" if there are none.
+func listOrNone(entries []string) string {
+	if len(entries) == 0 {
+		return "(none)"
+	}
+	return strings.Join(entries, ", ")
+}
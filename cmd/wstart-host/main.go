@@ -6,18 +6,25 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"strings"
 
 	"github.com/sverrirab/wsl-host-start/internal/allowlist"
+	"github.com/sverrirab/wsl-host-start/internal/config"
 	"github.com/sverrirab/wsl-host-start/internal/drives"
 	"github.com/sverrirab/wsl-host-start/internal/elevate"
+	"github.com/sverrirab/wsl-host-start/internal/envpolicy"
 	"github.com/sverrirab/wsl-host-start/internal/install"
+	"github.com/sverrirab/wsl-host-start/internal/pathconv"
 	"github.com/sverrirab/wsl-host-start/internal/protocol"
+	"github.com/sverrirab/wsl-host-start/internal/rpc"
 	"github.com/sverrirab/wsl-host-start/internal/shellexec"
 	"github.com/sverrirab/wsl-host-start/internal/signing"
 )
@@ -26,11 +33,15 @@ var version = "dev"
 
 func main() {
 	installMode := flag.Bool("install", false, "Install wstart to %LOCALAPPDATA%\\wstart and create default configs")
+	helloMode := flag.Bool("hello", false, "Print protocol version and capabilities as JSON to stdout")
 	drivesMode := flag.Bool("drives", false, "Enumerate drives and print JSON to stdout")
 	launchMode := flag.Bool("launch", false, "Read LaunchRequest from stdin, execute via ShellExecuteEx, print LaunchResponse to stdout")
+	requestMode := flag.Bool("request", false, "Read a combined Request from stdin (hello, drives, launch), print a Response to stdout")
+	serveMode := flag.Bool("serve", false, "Serve newline-delimited JSON-RPC requests (hello, drives, launch, check) on stdin until EOF")
 	execMode := flag.Bool("exec", false, "Read LaunchRequest from stdin, execute with stdio passthrough, exit with child's exit code")
 	checkConfig := flag.Bool("check-config", false, "Print active configuration diagnostics and exit")
 	signConfig := flag.Bool("sign-config", false, "Re-sign config files after editing (stores key in Windows Registry)")
+	pinProgram := flag.String("pin", "", "Pin the SHA-256 hash of `program` in its allowlist rules and re-sign the config")
 	verbose := flag.Bool("verbose", false, "Print extra detail in check-config output")
 	versionFlag := flag.Bool("version", false, "Print version")
 	flag.Parse()
@@ -38,6 +49,10 @@ func main() {
 	switch {
 	case *versionFlag:
 		fmt.Println(version)
+	case *helloMode:
+		if err := runHello(); err != nil {
+			fatal(err)
+		}
 	case *installMode:
 		if elevated, err := elevate.RequireElevation(os.Args[1:]); err != nil {
 			fatal(err)
@@ -73,6 +88,15 @@ func main() {
 		if err := runSignConfig(); err != nil {
 			fatal(err)
 		}
+	case *pinProgram != "":
+		if elevated, err := elevate.RequireElevation(os.Args[1:]); err != nil {
+			fatal(err)
+		} else if elevated {
+			return
+		}
+		if err := runPin(*pinProgram); err != nil {
+			fatal(err)
+		}
 	case *drivesMode:
 		if err := runDrives(); err != nil {
 			fatal(err)
@@ -81,6 +105,14 @@ func main() {
 		if err := runLaunch(); err != nil {
 			fatal(err)
 		}
+	case *requestMode:
+		if err := runRequest(); err != nil {
+			fatal(err)
+		}
+	case *serveMode:
+		if err := runServe(); err != nil {
+			fatal(err)
+		}
 	case *execMode:
 		runExec()
 	default:
@@ -89,6 +121,18 @@ func main() {
 	}
 }
 
+func runHello() error {
+	return json.NewEncoder(os.Stdout).Encode(hello())
+}
+
+func hello() *protocol.HelloResponse {
+	return &protocol.HelloResponse{
+		ProtocolVersion: protocol.ProtocolVersion,
+		Version:         version,
+		Capabilities:    protocol.Capabilities(),
+	}
+}
+
 func runDrives() error {
 	resp, err := drives.Enumerate()
 	if err != nil {
@@ -121,6 +165,45 @@ func runSignConfig() error {
 	return nil
 }
 
+// runPin adds the SHA-256 hash of the executable program resolves to to
+// the allowlist rules that name it (see allowlist.Pin), then re-signs the
+// configs. The signatures are verified first so that a tampered allowlist
+// is never signed.
+func runPin(program string) error {
+	_, al, err := loadAndVerify()
+	if err != nil {
+		return err
+	}
+	if !al.Loaded {
+		return fmt.Errorf("no %s in %s; pinning needs an [[allow]] rule for %s", allowlist.AllowlistFile, filepath.Dir(al.Path), program)
+	}
+	resolved := shellexec.Resolve(program)
+	if !filepath.IsAbs(resolved) {
+		return fmt.Errorf("%s: executable not found on PATH", program)
+	}
+	digest, err := allowlist.HashFile(resolved)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(al.Path)
+	if err != nil {
+		return err
+	}
+	pinned, n, err := allowlist.Pin(data, program, resolved, digest)
+	if err != nil {
+		return fmt.Errorf("%s: %w", al.Path, err)
+	}
+	if n == 0 {
+		fmt.Printf("%s\n  sha256 %s\n  is already pinned in %s\n", resolved, digest, al.Path)
+		return nil
+	}
+	if err := os.WriteFile(al.Path, pinned, 0644); err != nil {
+		return err
+	}
+	fmt.Printf("Pinned %s\n  sha256 %s\n  in %d rule(s) of %s\n", resolved, digest, n, al.Path)
+	return runSignConfig()
+}
+
 // loadAndVerify resolves the exe directory, verifies config signatures,
 // and loads the allowlist. This is the shared security gate for launch/exec.
 func loadAndVerify() (dir string, al *allowlist.LoadResult, err error) {
@@ -156,52 +239,285 @@ func runLaunch() error {
 		return fmt.Errorf("decoding launch request: %w", err)
 	}
 
-	_, al, err := loadAndVerify()
+	dir, al, err := loadAndVerify()
 	if err != nil {
-		return err
+		return json.NewEncoder(os.Stdout).Encode(failure(err))
+	}
+
+	return json.NewEncoder(os.Stdout).Encode(handleLaunch(&req, dir, al, nil))
+}
+
+// runRequest serves a combined request: handshake, drive table and launch
+// are answered in one Response so the WSL side needs a single spawn.
+func runRequest() error {
+	var req protocol.Request
+	if err := json.NewDecoder(os.Stdin).Decode(&req); err != nil {
+		return fmt.Errorf("decoding request: %w", err)
+	}
+	return json.NewEncoder(os.Stdout).Encode(serveRequest(&req))
+}
+
+func serveRequest(req *protocol.Request) *protocol.Response {
+	resp := &protocol.Response{}
+	h := hello()
+	if req.Hello {
+		resp.Hello = h
+	}
+	if missing := h.Missing(req.Require...); len(missing) > 0 {
+		resp.Error = fmt.Sprintf("wstart-host.exe %s does not support %q", version, missing[0])
+		return resp
+	}
+
+	var drv *protocol.DrivesResponse
+	if req.Drives || wantsAliases(req) {
+		// Non-fatal: without a drive table only config aliases apply.
+		drv, _ = drives.Enumerate()
+	}
+	if req.Drives {
+		resp.Drives = drv
+	}
+
+	if req.Launch == nil && len(req.Launches) == 0 {
+		return resp
+	}
+	dir, al, err := loadAndVerify()
+	if err != nil {
+		fail := failure(err)
+		if req.Launch != nil {
+			resp.Launch = fail
+		}
+		for range req.Launches {
+			resp.Launches = append(resp.Launches, *fail)
+		}
+		return resp
+	}
+	if req.Launch != nil {
+		resp.Launch = handleLaunch(req.Launch, dir, al, drv)
+	}
+	for i := range req.Launches {
+		resp.Launches = append(resp.Launches, *handleLaunch(&req.Launches[i], dir, al, drv))
+	}
+	return resp
+}
+
+// wantsAliases reports whether any launch in req asks for host-side alias resolution.
+func wantsAliases(req *protocol.Request) bool {
+	if req.Launch != nil && req.Launch.ResolveAliases {
+		return true
 	}
-	if err := al.Check(req.File, req.Args); err != nil {
-		resp := &protocol.LaunchResponse{
-			Error:   err.Error(),
-			ErrCode: 5, // SE_ERR_ACCESSDENIED
+	for _, l := range req.Launches {
+		if l.ResolveAliases {
+			return true
 		}
-		return json.NewEncoder(os.Stdout).Encode(resp)
 	}
+	return false
+}
 
-	resp := shellexec.Execute(&req)
+// runServe answers JSON-RPC requests on stdin until EOF, so a batch of
+// launches from WSL shares one helper process.
+func runServe() error {
+	return rpc.Serve(os.Stdin, os.Stdout, serveMethod)
+}
 
-	return json.NewEncoder(os.Stdout).Encode(resp)
+func serveMethod(method string, params json.RawMessage) (any, error) {
+	switch method {
+	case protocol.MethodHello:
+		return hello(), nil
+	case protocol.MethodDrives:
+		return drives.Enumerate()
+	case protocol.MethodLaunch, protocol.MethodCheck:
+		var req protocol.LaunchRequest
+		if err := json.Unmarshal(params, &req); err != nil {
+			return nil, rpc.InvalidParams(err)
+		}
+		// Config is re-verified per request: it may be re-signed mid-session.
+		dir, al, err := loadAndVerify()
+		if err == nil {
+			resolveAliases(&req, dir, nil)
+			err = checkRequest(al, &req)
+		}
+		if method == protocol.MethodCheck {
+			if err != nil {
+				f := failure(err)
+				return &protocol.CheckResponse{Reason: f.Error, Category: f.Category, Rule: f.Rule, Path: f.Path}, nil
+			}
+			return &protocol.CheckResponse{Allowed: true}, nil
+		}
+		if err != nil {
+			return failure(err), nil
+		}
+		dropped := enforceEnv(&req, dir)
+		resp := shellexec.Execute(&req)
+		resp.DroppedEnv = dropped
+		return resp, nil
+	default:
+		return nil, rpc.MethodNotFound(method)
+	}
+}
+
+// handleLaunch applies host-side alias resolution and the allowlist, then runs
+// the request via ShellExecuteEx. drv may be nil, in which case drives are
+// enumerated on demand.
+func handleLaunch(req *protocol.LaunchRequest, dir string, al *allowlist.LoadResult, drv *protocol.DrivesResponse) *protocol.LaunchResponse {
+	resolveAliases(req, dir, drv)
+
+	if err := checkRequest(al, req); err != nil {
+		return failure(err)
+	}
+	dropped := enforceEnv(req, dir)
+	resp := shellexec.Execute(req)
+	resp.DroppedEnv = dropped
+	return resp
+}
+
+// enforceEnv re-applies the [env] policy of the signed config, scoped to
+// the launched program, to req.EnvVars: any WSL process can write its own
+// request, so the filtering done by wstart cannot be relied on. It returns
+// the removed variables, described for the user. If the config cannot be
+// loaded, nothing is forwarded.
+func enforceEnv(req *protocol.LaunchRequest, dir string) []string {
+	if len(req.EnvVars) == 0 {
+		return nil
+	}
+	policy := envpolicy.Policy{}
+	if cfg, err := config.Load(dir); err == nil {
+		env := cfg.Env.ForProgram(allowlist.ProgramName(req.File))
+		// With merge_wslenv the forward list depends on $WSLENV on the
+		// WSL side, which the helper cannot see, so only the block list
+		// is enforced; the config template says so.
+		policy = envpolicy.Policy{
+			Forward:    env.Forward,
+			Block:      env.Block,
+			AnyForward: env.MergeWSLENV,
+		}
+		if env.AllowAdHoc {
+			policy.AdHoc = req.AdHocEnv
+		}
+	}
+	kept, removed := policy.Enforce(req.EnvVars)
+	req.EnvVars = kept
+	dropped := make([]string, len(removed))
+	for i, r := range removed {
+		dropped[i] = r.String()
+	}
+	return dropped
+}
+
+// checkRequest resolves the executable req starts and checks the request
+// against the allowlist. If it is allowed, req.File is replaced by the
+// resolved path, so that exactly the executable that was checked runs.
+func checkRequest(al *allowlist.LoadResult, req *protocol.LaunchRequest) error {
+	// Resolve returns the name unchanged if the search finds nothing; only
+	// an absolute path is an executable the rules can check.
+	var resolved string
+	if pathconv.URIScheme(req.File) == "" {
+		if r := shellexec.Resolve(req.File); filepath.IsAbs(r) {
+			resolved = r
+		}
+	}
+	err := al.CheckRequest(allowlist.Request{
+		File:     req.File,
+		Resolved: resolved,
+		Args:     req.Args,
+		CmdLine:  req.CmdLine,
+		Verb:     req.Verb,
+	})
+	if err != nil {
+		return err
+	}
+	if resolved != "" {
+		req.File = resolved
+	}
+	return nil
+}
+
+// failure converts a policy or verification error into a categorized
+// LaunchResponse so the WSL side can give targeted guidance.
+func failure(err error) *protocol.LaunchResponse {
+	resp := &protocol.LaunchResponse{
+		Error:    err.Error(),
+		Category: protocol.ErrInternal,
+	}
+	var deny *allowlist.DenyError
+	var sig *signing.SignatureError
+	switch {
+	case errors.As(err, &deny):
+		resp.ErrCode = 5 // SE_ERR_ACCESSDENIED
+		resp.Category = deny.Category
+		resp.Rule = deny.Rule
+		resp.Path = deny.Path
+	case errors.As(err, &sig):
+		resp.Category = protocol.ErrSignatureInvalid
+		resp.Path = sig.Path
+	}
+	return resp
+}
+
+// resolveAliases rewrites req.File and req.WorkDir to their aliased drive
+// form when the WSL side asked for it (its drive cache was stale).
+func resolveAliases(req *protocol.LaunchRequest, dir string, drv *protocol.DrivesResponse) {
+	if !req.ResolveAliases {
+		return
+	}
+	cfg, err := config.Load(dir)
+	if err != nil || !cfg.Drives.PreferAliases {
+		return
+	}
+	if drv == nil && cfg.Drives.AutoDetect {
+		drv, _ = drives.Enumerate()
+	}
+	var list []protocol.DriveInfo
+	if drv != nil && cfg.Drives.AutoDetect {
+		list = drv.Drives
+	}
+	conv := pathconv.NewConverter(list, cfg.Drives.Aliases, true)
+	req.File = conv.ApplyAlias(req.File)
+	if req.WorkDir != "" {
+		req.WorkDir = conv.ApplyAlias(req.WorkDir)
+	}
 }
 
 // runExec executes a command with stdio passthrough (for -wait mode).
-// The helper's exit code becomes the child's exit code.
+// The helper's exit code becomes the child's exit code; the helper's own
+// failures use the reserved protocol.Exit* codes.
 func runExec() {
 	dec := json.NewDecoder(os.Stdin)
 	var req protocol.LaunchRequest
 	if err := dec.Decode(&req); err != nil {
-		fmt.Fprintf(os.Stderr, "wstart-host: decoding request: %v\n", err)
-		os.Exit(1)
+		execFatal(fmt.Errorf("decoding request: %w", err))
 	}
 
-	_, al, err := loadAndVerify()
+	dir, al, err := loadAndVerify()
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "wstart-host: %v\n", err)
-		os.Exit(1)
+		execFatal(err)
+	}
+	resolveAliases(&req, dir, nil)
+	if err := checkRequest(al, &req); err != nil {
+		execFatal(err)
 	}
-	if err := al.Check(req.File, req.Args); err != nil {
-		fmt.Fprintf(os.Stderr, "wstart-host: %v\n", err)
-		os.Exit(5) // SE_ERR_ACCESSDENIED
+	for _, d := range enforceEnv(&req, dir) {
+		fmt.Fprintf(os.Stderr, "wstart-host: not forwarding environment variable %s\n", d)
 	}
 
 	stdin := io.MultiReader(dec.Buffered(), os.Stdin)
 	exitCode, err := shellexec.ExecuteConsole(&req, stdin)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "wstart-host: %v\n", err)
-		os.Exit(1)
+		if errors.Is(err, exec.ErrNotFound) || errors.Is(err, fs.ErrNotExist) {
+			fmt.Fprintf(os.Stderr, "wstart-host: %v\n", err)
+			os.Exit(protocol.ExitNotFound)
+		}
+		execFatal(err)
 	}
 	os.Exit(exitCode)
 }
 
+// execFatal reports a helper failure in exec mode, exiting with the
+// reserved code for its category so wstart can pass it through unchanged.
+func execFatal(err error) {
+	fmt.Fprintf(os.Stderr, "wstart-host: %v\n", err)
+	os.Exit(protocol.ExitCodeFor(failure(err).Category))
+}
+
 // configDir returns the directory containing config files.
 // Prefers the install directory (%LOCALAPPDATA%\wstart) if it exists,
 // otherwise falls back to the directory containing the running executable.
@@ -2,9 +2,12 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"os"
+	"path/filepath"
+	"slices"
 
 	"github.com/sverrirab/wsl-host-start/internal/launch"
 	"github.com/sverrirab/wsl-host-start/internal/protocol"
@@ -13,20 +16,44 @@ import (
 var version = "dev"
 
 func main() {
-	verb := flag.String("verb", "", "ShellExecuteEx verb: open, runas, edit, print, explore, properties")
-	dir := flag.String("dir", "", "Working directory (WSL or Windows path)")
-	wait := flag.Bool("wait", false, "Wait for the launched process to exit")
-	min := flag.Bool("min", false, "Start window minimized")
-	max := flag.Bool("max", false, "Start window maximized")
-	hidden := flag.Bool("hidden", false, "Start window hidden")
-	dryRun := flag.Bool("dry-run", false, "Print translated command without executing")
-	verbose := flag.Bool("verbose", false, "Print diagnostic info")
-	refreshDrives := flag.Bool("refresh-drives", false, "Refresh drive cache and exit")
-	checkConfig := flag.Bool("check-config", false, "Print active configuration diagnostics and exit")
-	versionFlag := flag.Bool("version", false, "Print version")
-
-	flag.Usage = func() {
-		fmt.Fprintf(os.Stderr, "Usage: wstart [flags] <target> [args...]\n\n")
+	if name := filepath.Base(os.Args[0]); slices.Contains(launch.XdgOpenNames, name) {
+		xdgOpenMain(name, os.Args[1:], &launch.Options{})
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "path" {
+		pathMain(os.Args[2:])
+		return
+	}
+
+	fs := flag.NewFlagSet("wstart", flag.ContinueOnError)
+	verb := fs.String("verb", "", "ShellExecuteEx verb: open, runas, edit, print, explore, properties")
+	dir := fs.String("dir", "", "Working directory (WSL or Windows path)")
+	wait := fs.Bool("wait", false, "Wait for the launched process to exit")
+	min := fs.Bool("min", false, "Start window minimized")
+	max := fs.Bool("max", false, "Start window maximized")
+	hidden := fs.Bool("hidden", false, "Start window hidden")
+	each := fs.Bool("each", false, "Treat every argument as a separate target (no program arguments)")
+	batch := fs.Bool("batch", false, "Read targets from stdin (one per line) and launch each over one helper session")
+	dryRun := fs.Bool("dry-run", false, "Print translated command without executing")
+	rawArgs := fs.Bool("raw-args", false, "Pass program arguments through without translating paths in them")
+	ext := fs.String("ext", "", "Extension of the temp file when the target is - (stdin), e.g. .html (default .txt)")
+	cmdLine := fs.String("cmdline", "", "Pass this command line to the program verbatim instead of quoted arguments")
+	env := envVars{}
+	fs.Var(envAssign(env), "env", "Set `NAME=VALUE` for this launch only (repeatable; blocked variables are refused)")
+	fs.Var(envFile(env), "env-from", "Set the NAME=VALUE lines of `file` for this launch only")
+	verbose := fs.Bool("verbose", false, "Print diagnostic info")
+	timing := fs.Bool("timing", false, "Print per-phase latency (helper discovery, handshake, helper round trip)")
+	refreshDrives := fs.Bool("refresh-drives", false, "Refresh drive cache and exit")
+	toWSL := fs.Bool("to-wsl", false, "Print the WSL path of each Windows path argument (expands drive aliases)")
+	xdgOpen := fs.Bool("xdg-open", false, "Behave like xdg-open: open exactly one file or URL, never wait, use xdg-open exit codes")
+	checkConfig := fs.Bool("check-config", false, "Print active configuration diagnostics and exit")
+	versionFlag := fs.Bool("version", false, "Print version")
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: wstart [flags] <target> [args...]\n")
+		fmt.Fprintf(os.Stderr, "       wstart -each [flags] <target>...\n")
+		fmt.Fprintf(os.Stderr, "       wstart path [-w|-m|-u] [-a] [path...]\n")
+		fmt.Fprintf(os.Stderr, "       wstart -xdg-open <file|URL>   (also as xdg-open, wslview, sensible-browser)\n\n")
 		fmt.Fprintf(os.Stderr, "Launch Windows programs from WSL via ShellExecuteEx.\n\n")
 		fmt.Fprintf(os.Stderr, "Examples:\n")
 		fmt.Fprintf(os.Stderr, "  wstart document.pdf            Open in default PDF viewer\n")
@@ -35,12 +62,17 @@ func main() {
 		fmt.Fprintf(os.Stderr, "  wstart -verb runas cmd.exe     Launch elevated command prompt\n")
 		fmt.Fprintf(os.Stderr, "  wstart -verb print report.docx Print a document\n")
 		fmt.Fprintf(os.Stderr, "  wstart -wait installer.exe     Wait for process to exit\n")
+		fmt.Fprintf(os.Stderr, "  wstart -env P4CLIENT=ws2 p4v   Set a variable for this launch only\n")
+		fmt.Fprintf(os.Stderr, "  wstart -each *.pdf             Open every PDF, not pass them as arguments\n")
+		fmt.Fprintf(os.Stderr, "  ls *.pdf | wstart -batch       Open every file listed on stdin\n")
+		fmt.Fprintf(os.Stderr, "  report | wstart -ext .html -   Open stdin as a temporary .html file\n")
+		fmt.Fprintf(os.Stderr, "  wstart -to-wsl 'P:\\src\\a.c'    Print the WSL path of a Windows path\n")
 		fmt.Fprintf(os.Stderr, "  wstart -check-config           Show active config diagnostics\n\n")
 		fmt.Fprintf(os.Stderr, "Flags:\n")
-		flag.PrintDefaults()
+		fs.PrintDefaults()
 	}
 
-	flag.Parse()
+	parseFlags(fs, os.Args[1:])
 
 	if *versionFlag {
 		fmt.Println(version)
@@ -63,9 +95,19 @@ func main() {
 		return
 	}
 
-	if flag.NArg() < 1 {
-		flag.Usage()
-		os.Exit(1)
+	if *toWSL {
+		if fs.NArg() < 1 {
+			fs.Usage()
+			os.Exit(protocol.ExitInternal)
+		}
+		for _, arg := range fs.Args() {
+			p, err := launch.ToWSL(arg)
+			if err != nil {
+				fatal(err)
+			}
+			fmt.Println(p)
+		}
+		return
 	}
 
 	show := protocol.ShowNormal
@@ -79,16 +121,48 @@ func main() {
 	}
 
 	opts := &launch.Options{
-		Target:  flag.Arg(0),
-		Args:    flag.Args()[1:],
 		Verb:    *verb,
 		WorkDir: *dir,
 		Show:    show,
 		Wait:    *wait,
 		DryRun:  *dryRun,
+		RawArgs: *rawArgs,
+		CmdLine: *cmdLine,
+		Env:     env,
+		Ext:     *ext,
 		Verbose: *verbose,
+		Timing:  *timing,
+	}
+
+	if *xdgOpen {
+		xdgOpenMain("wstart", fs.Args(), opts)
+	}
+
+	if *batch {
+		opts.Args = fs.Args()
+		result, err := launch.RunBatch(opts, os.Stdin)
+		if err != nil {
+			fatal(err)
+		}
+		os.Exit(result.ExitCode)
+	}
+
+	if fs.NArg() < 1 {
+		fs.Usage()
+		os.Exit(protocol.ExitInternal)
+	}
+
+	if *each {
+		result, err := launch.RunEach(opts, fs.Args())
+		if err != nil {
+			fatal(err)
+		}
+		os.Exit(result.ExitCode)
 	}
 
+	opts.Target = fs.Arg(0)
+	opts.Args = fs.Args()[1:]
+
 	result, err := launch.Run(opts)
 	if err != nil {
 		fatal(err)
@@ -97,7 +171,59 @@ func main() {
 	os.Exit(result.ExitCode)
 }
 
+// envVars collects the variables of -env and -env-from. Later flags
+// override earlier ones.
+type envVars map[string]string
+
+// envAssign is the -env flag: one NAME=VALUE per use.
+type envAssign envVars
+
+func (e envAssign) String() string { return "" }
+
+func (e envAssign) Set(s string) error {
+	name, value, err := launch.ParseEnvAssignment(s)
+	if err != nil {
+		return err
+	}
+	e[name] = value
+	return nil
+}
+
+// envFile is the -env-from flag: a file of NAME=VALUE lines.
+type envFile envVars
+
+func (e envFile) String() string { return "" }
+
+func (e envFile) Set(path string) error {
+	vars, err := launch.ReadEnvFile(path)
+	if err != nil {
+		return err
+	}
+	for name, value := range vars {
+		e[name] = value
+	}
+	return nil
+}
+
+// parseFlags parses args with fs, which must use flag.ContinueOnError.
+// Parse has already printed the error and usage when it fails; -h exits 0,
+// and a bad flag, being a failure of wstart itself, exits with the
+// reserved code rather than the flag package's 2.
+func parseFlags(fs *flag.FlagSet, args []string) {
+	err := fs.Parse(args)
+	switch {
+	case err == nil:
+	case errors.Is(err, flag.ErrHelp):
+		os.Exit(0)
+	default:
+		os.Exit(protocol.ExitInternal)
+	}
+}
+
+// fatal reports a failure of wstart itself and exits with the reserved
+// code for it, so callers can tell it apart from the launched program's
+// own exit status.
 func fatal(err error) {
 	fmt.Fprintf(os.Stderr, "wstart: %v\n", err)
-	os.Exit(1)
+	os.Exit(launch.ExitCode(err))
 }
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/sverrirab/wsl-host-start/internal/protocol"
+)
+
+// TestMain runs main itself when WSTART_TEST_MAIN is set, with the
+// space-separated arguments it holds, so tests can check its exit code.
+func TestMain(m *testing.M) {
+	if args, ok := os.LookupEnv("WSTART_TEST_MAIN"); ok {
+		os.Args = append([]string{"wstart"}, strings.Fields(args)...)
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+func TestFlagErrorExitCode(t *testing.T) {
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal("os.Executable:", err)
+	}
+	tests := []struct {
+		args string
+		want int
+	}{
+		{"-bogus a.txt", protocol.ExitInternal},
+		{"-env NOVALUE a.txt", protocol.ExitInternal},
+		{"-env-from /nonexistent/env a.txt", protocol.ExitInternal},
+		{"-h", 0},
+		{"path -bogus", protocol.ExitInternal},
+		{"path -h", 0},
+	}
+	for _, tt := range tests {
+		cmd := exec.Command(exe)
+		cmd.Env = append(os.Environ(), "WSTART_TEST_MAIN="+tt.args)
+		err := cmd.Run()
+		code := 0
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			code = exitErr.ExitCode()
+		} else if err != nil {
+			t.Fatalf("%s: %v", tt.args, err)
+		}
+		if code != tt.want {
+			t.Errorf("wstart %s: exit code = %d, want %d", tt.args, code, tt.want)
+		}
+	}
+}
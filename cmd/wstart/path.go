@@ -0,0 +1,36 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/sverrirab/wsl-host-start/internal/launch"
+)
+
+// pathMain implements "wstart path", an alias-aware replacement for wslpath.
+func pathMain(args []string) {
+	fs := flag.NewFlagSet("path", flag.ContinueOnError)
+	toWindows := fs.Bool("w", false, "Translate from a WSL path to a Windows path")
+	mixed := fs.Bool("m", false, "Translate from a WSL path to a Windows path, with '/' instead of '\\'")
+	fs.Bool("u", false, "Translate from a Windows path to a WSL path (default)")
+	absolute := fs.Bool("a", false, "Force result to absolute path format")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: wstart path [-w|-m|-u] [-a] [path...]\n\n")
+		fmt.Fprintf(os.Stderr, "Translate paths like wslpath, applying drive aliases from the drive\n")
+		fmt.Fprintf(os.Stderr, "cache and config. Reads one path per line from stdin if none are given.\n\n")
+		fmt.Fprintf(os.Stderr, "Flags:\n")
+		fs.PrintDefaults()
+	}
+	parseFlags(fs, args)
+
+	result, err := launch.RunPath(&launch.PathOptions{
+		Windows:  *toWindows,
+		Mixed:    *mixed,
+		Absolute: *absolute,
+	}, fs.Args(), os.Stdin)
+	if err != nil {
+		fatal(err)
+	}
+	os.Exit(result.ExitCode)
+}
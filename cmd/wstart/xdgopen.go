@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/sverrirab/wsl-host-start/internal/launch"
+)
+
+// xdgOpenMain implements xdg-open compatibility. wstart runs it when
+// invoked as xdg-open, wslview or sensible-browser (e.g. through a
+// symlink), or with -xdg-open. name is used in messages.
+func xdgOpenMain(name string, args []string, opts *launch.Options) {
+	for _, a := range args {
+		switch a {
+		case "--help", "--manual":
+			fmt.Printf("Usage: %s { file | URL }\n\n", name)
+			fmt.Printf("Open a file or URL on the Windows host with its default application.\n")
+			fmt.Printf("Implemented by wstart; exit codes follow xdg-open(1).\n")
+			os.Exit(launch.XdgExitOK)
+		case "--version":
+			fmt.Printf("%s (wstart) %s\n", name, version)
+			os.Exit(launch.XdgExitOK)
+		}
+	}
+
+	target, err := launch.XdgOpenArgs(args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", name, err)
+		fmt.Fprintf(os.Stderr, "Try '%s --help' for more information.\n", name)
+		os.Exit(launch.XdgExitUsage)
+	}
+
+	launch.Version = version
+	if err := launch.RunXdgOpen(opts, target); err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", name, err)
+		os.Exit(launch.XdgExitCode(err))
+	}
+	os.Exit(launch.XdgExitOK)
+}
@@ -7,14 +7,21 @@
 package allowlist
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"io"
 	"os"
+	"path"
 	"path/filepath"
 	"sort"
 	"strings"
 
 	"github.com/BurntSushi/toml"
+
+	"github.com/sverrirab/wsl-host-start/internal/pathconv"
+	"github.com/sverrirab/wsl-host-start/internal/protocol"
 )
 
 // AllowlistFile is the expected filename in the helper's directory.
@@ -31,10 +38,76 @@ type Rule struct {
 	// argument (skipping flags that start with "-") is checked against
 	// this list. If empty, any arguments are allowed.
 	Commands []string `toml:"commands,omitempty"`
+
+	// Path pins the full path of the executable the request resolves to on
+	// the host, e.g. "C:\Program Files\Perforce\p4.exe". It may contain
+	// glob patterns. If Program is empty, it is taken from Path.
+	Path string `toml:"path,omitempty"`
+
+	// Dir requires the resolved executable to lie in a matching directory
+	// or below one, e.g. "C:\Program Files\*".
+	Dir string `toml:"dir,omitempty"`
+
+	// SHA256 lists the hex SHA-256 digests the resolved executable may
+	// have (see wstart-host --pin). If set, any other binary is denied.
+	SHA256 []string `toml:"sha256,omitempty"`
+
+	// Verbs lists the ShellExecuteEx verbs the program may be launched
+	// with (e.g. "open", "edit", "runas"). If empty, the [policy] verbs
+	// apply.
+	Verbs []string `toml:"verbs,omitempty"`
+}
+
+// Request is a launch as the allowlist sees it.
+type Request struct {
+	// File is the target as requested: a program name, a path or a URI.
+	File string
+	// Resolved is the absolute path of the executable File resolves to on
+	// the host (after the PATH and PATHEXT search), or "" if it was not
+	// resolved. A relative path counts as unresolved. Rules with a path,
+	// dir or sha256 never match an unresolved request.
+	Resolved string
+	Args     []string
+	// CmdLine is a raw command line that replaces Args. The program may
+	// split it differently from any split done here, so a rule with
+	// commands never allows a request that has one.
+	CmdLine string
+	// Verb is the ShellExecuteEx verb; "" means "open".
+	Verb string
+}
+
+// Elevation settings for the runas verb ([policy] elevation).
+const (
+	// ElevationDeny refuses runas for every program.
+	ElevationDeny = "deny"
+	// ElevationAllowlisted allows runas only where a rule's verbs list it.
+	ElevationAllowlisted = "allowlisted"
+	// ElevationAny allows runas for every program the allowlist permits.
+	ElevationAny = "any"
+)
+
+// VerbRunas is the verb that launches a program elevated.
+const VerbRunas = "runas"
+
+// Policy holds the allowlist-wide settings of the [policy] table.
+type Policy struct {
+	// Elevation is ElevationDeny, ElevationAllowlisted (the default) or
+	// ElevationAny.
+	Elevation string `toml:"elevation,omitempty"`
+
+	// Verbs are the verbs allowed by rules without their own verbs list.
+	// Defaults to "open" only.
+	Verbs []string `toml:"verbs,omitempty"`
 }
 
 // List holds parsed allowlist rules.
 type List struct {
+	// Schemes lists the URI schemes (e.g. "https", "mailto") that may be
+	// opened. URIs are checked against it instead of the program rules.
+	Schemes []string `toml:"schemes,omitempty"`
+
+	Policy Policy `toml:"policy"`
+
 	Allow []Rule `toml:"allow"`
 }
 
@@ -46,6 +119,10 @@ type LoadResult struct {
 	Path string
 	// List contains the rules (nil if not loaded).
 	List *List
+
+	// hashFile computes the digest checked against Rule.SHA256; nil means
+	// HashFile. Tests replace it.
+	hashFile func(path string) (string, error)
 }
 
 // Load reads the allowlist from the given directory (typically the
@@ -62,6 +139,20 @@ func Load(dir string) (*LoadResult, error) {
 		}
 		return nil, fmt.Errorf("parsing %s: %w", path, err)
 	}
+	switch list.Policy.Elevation {
+	case "":
+		list.Policy.Elevation = ElevationAllowlisted
+	case ElevationDeny, ElevationAllowlisted, ElevationAny:
+	default:
+		return nil, fmt.Errorf("parsing %s: [policy] elevation = %q is not one of %q, %q or %q",
+			path, list.Policy.Elevation, ElevationDeny, ElevationAllowlisted, ElevationAny)
+	}
+	for _, verb := range list.Policy.Verbs {
+		if normalizeVerb(verb) == VerbRunas {
+			return nil, fmt.Errorf("parsing %s: [policy] verbs may not list %q; list it in the verbs of each rule that may run elevated",
+				path, verb)
+		}
+	}
 
 	result.Loaded = true
 	result.List = &list
@@ -83,6 +174,27 @@ var denyList = map[string]bool{
 	"bash":      true,
 }
 
+// DenyError is returned by Check and CheckDenyList when a request is not
+// permitted. It carries enough detail for the WSL side to explain the denial.
+type DenyError struct {
+	// Category is protocol.ErrDeniedDenylist, protocol.ErrDeniedAllowlist
+	// or protocol.ErrHashMismatch.
+	Category string
+	// Program is the normalized program name that was checked.
+	Program string
+	// Rule is the deny list entry or allowlist rule that denied the
+	// request; empty if no allowlist rule matched the program at all.
+	Rule string
+	// Path is the allowlist file (empty for deny list denials).
+	Path string
+
+	msg string
+}
+
+func (e *DenyError) Error() string {
+	return e.msg
+}
+
 // DeniedPrograms returns the list of programs that are unconditionally blocked.
 func DeniedPrograms() []string {
 	names := make([]string, 0, len(denyList))
@@ -98,42 +210,123 @@ func DeniedPrograms() []string {
 func CheckDenyList(file string) error {
 	baseName := normalizeProgram(file)
 	if denyList[baseName] {
-		return fmt.Errorf("denied: %q is a blocked program (hardcoded deny list — cannot be overridden)", baseName)
+		return &DenyError{
+			Category: protocol.ErrDeniedDenylist,
+			Program:  baseName,
+			Rule:     baseName,
+			msg:      fmt.Sprintf("denied: %q is a blocked program (hardcoded deny list — cannot be overridden)", baseName),
+		}
 	}
 	return nil
 }
 
-// Check verifies that the given file and args are permitted by the allowlist.
-// Returns nil if allowed, or an error describing why the request was denied.
+// Check verifies that the given file and args are permitted by the
+// allowlist, without a resolved executable (see CheckRequest).
+func (lr *LoadResult) Check(file string, args []string) error {
+	return lr.CheckRequest(Request{File: file, Args: args})
+}
+
+// CheckRequest verifies that req is permitted by the allowlist.
+// Returns nil if allowed, or a *DenyError describing why the request was denied.
 //
-// The hardcoded deny list is always checked first, regardless of allowlist state.
+// The hardcoded deny list is always checked first, against both the
+// requested and the resolved program, regardless of allowlist state.
 // If no allowlist was loaded (lr.Loaded == false), non-denied programs are allowed.
-func (lr *LoadResult) Check(file string, args []string) error {
-	if err := CheckDenyList(file); err != nil {
+//
+// A file: URI is checked as the path it names; other URIs are checked
+// against the allowed schemes. The elevation policy applies to both.
+func (lr *LoadResult) CheckRequest(req Request) error {
+	verb := normalizeVerb(req.Verb)
+	scheme := pathconv.URIScheme(req.File)
+	if lr.Loaded && verb == VerbRunas && lr.List.Policy.Elevation == ElevationDeny {
+		program := normalizeProgram(req.File)
+		if scheme != "" {
+			program = scheme + ":"
+		}
+		return &DenyError{
+			Category: protocol.ErrDeniedAllowlist,
+			Program:  program,
+			Rule:     fmt.Sprintf("[policy] elevation = %q", ElevationDeny),
+			Path:     lr.Path,
+			msg:      fmt.Sprintf("denied: %q may not be launched elevated (elevation is denied in %s)", program, lr.Path),
+		}
+	}
+
+	switch scheme {
+	case "":
+	case "file":
+		winPath, ok := pathconv.FileURIPath(req.File)
+		if !ok {
+			return &DenyError{
+				Category: protocol.ErrDeniedAllowlist,
+				Program:  req.File,
+				Path:     lr.Path,
+				msg:      fmt.Sprintf("denied: %q does not name a Windows path", req.File),
+			}
+		}
+		req.File = winPath
+		if req.Resolved == "" {
+			req.Resolved = winPath
+		}
+	default:
+		return lr.checkScheme(scheme, verb)
+	}
+	if !isAbs(req.Resolved) {
+		req.Resolved = ""
+	}
+	if err := CheckDenyList(req.File); err != nil {
 		return err
 	}
+	if req.Resolved != "" {
+		if err := CheckDenyList(req.Resolved); err != nil {
+			return err
+		}
+	}
 
 	if !lr.Loaded {
 		return nil
 	}
 
-	baseName := normalizeProgram(file)
+	baseName := normalizeProgram(req.File)
+	policy := lr.List.Policy
 
-	var matched bool
+	var matched, misplaced, mismatched, wrongVerb, rawCmdLine []Rule
 	var allCommands []string
+	var digest string
+	var digestErr error
 
 	for _, rule := range lr.List.Allow {
-		if !matchProgram(baseName, rule.Program) {
+		if !rule.matchesName(baseName) {
+			continue
+		}
+		if !rule.matchesLocation(req.Resolved) {
+			misplaced = append(misplaced, rule)
+			continue
+		}
+		if len(rule.SHA256) > 0 {
+			if digest == "" && digestErr == nil {
+				digest, digestErr = lr.digest(req.Resolved)
+			}
+			if digestErr != nil || !rule.matchesDigest(digest) {
+				mismatched = append(mismatched, rule)
+				continue
+			}
+		}
+		if !rule.allowsVerb(verb, policy) {
+			wrongVerb = append(wrongVerb, rule)
 			continue
 		}
-		matched = true
-
 		// Program matches. Check subcommand restriction.
 		if len(rule.Commands) == 0 {
 			return nil // No subcommand restriction — allow all.
 		}
+		if req.CmdLine != "" {
+			rawCmdLine = append(rawCmdLine, rule)
+			continue
+		}
+		matched = append(matched, rule)
 
-		subcmd := firstPositionalArg(args)
+		subcmd := firstPositionalArg(req.Args)
 		for _, allowed := range rule.Commands {
 			if strings.EqualFold(subcmd, allowed) {
 				return nil
@@ -142,18 +335,251 @@ func (lr *LoadResult) Check(file string, args []string) error {
 		allCommands = append(allCommands, rule.Commands...)
 	}
 
-	if matched {
-		subcmd := firstPositionalArg(args)
+	deny := &DenyError{
+		Category: protocol.ErrDeniedAllowlist,
+		Program:  baseName,
+		Path:     lr.Path,
+	}
+	if len(matched) > 0 {
+		deny.Rule = matched[0].String()
+		subcmd := firstPositionalArg(req.Args)
 		if subcmd == "" {
-			return fmt.Errorf("denied: %q requires a subcommand (allowed: %s)",
+			deny.msg = fmt.Sprintf("denied: %q requires a subcommand (allowed: %s)",
 				baseName, strings.Join(allCommands, ", "))
+		} else {
+			deny.msg = fmt.Sprintf("denied: %q subcommand %q is not allowed (allowed: %s)",
+				baseName, subcmd, strings.Join(allCommands, ", "))
+		}
+		return deny
+	}
+	if len(rawCmdLine) > 0 {
+		deny.Rule = rawCmdLine[0].String()
+		deny.msg = fmt.Sprintf("denied: %q is restricted to subcommands (%s), which a raw command line cannot be checked against",
+			baseName, strings.Join(rawCmdLine[0].Commands, ", "))
+		return deny
+	}
+	if len(wrongVerb) > 0 {
+		deny.Rule = wrongVerb[0].String()
+		deny.msg = fmt.Sprintf("denied: %q may not be launched with verb %q (allowed: %s)",
+			baseName, verb, strings.Join(wrongVerb[0].verbs(policy), ", "))
+		return deny
+	}
+	if len(mismatched) > 0 {
+		deny.Category = protocol.ErrHashMismatch
+		deny.Rule = mismatched[0].String()
+		switch {
+		case req.Resolved == "":
+			deny.msg = fmt.Sprintf("denied: %q could not be resolved to an executable to verify its SHA-256 hash", baseName)
+		case digestErr != nil:
+			deny.msg = fmt.Sprintf("denied: cannot verify the SHA-256 hash of %q: %v", req.Resolved, digestErr)
+		default:
+			deny.msg = fmt.Sprintf("denied: SHA-256 hash of %q (%s) is not pinned by rule %q",
+				req.Resolved, digest, deny.Rule)
+		}
+		return deny
+	}
+	if len(misplaced) > 0 {
+		deny.Rule = misplaced[0].String()
+		if req.Resolved == "" {
+			deny.msg = fmt.Sprintf("denied: %q could not be resolved to an executable, which rule %q requires",
+				baseName, deny.Rule)
+		} else {
+			deny.msg = fmt.Sprintf("denied: %q resolves to %q, which rule %q does not allow",
+				baseName, req.Resolved, deny.Rule)
 		}
-		return fmt.Errorf("denied: %q subcommand %q is not allowed (allowed: %s)",
-			baseName, subcmd, strings.Join(allCommands, ", "))
+		return deny
 	}
 
-	return fmt.Errorf("denied: program %q is not in the allowlist (%s)",
+	deny.msg = fmt.Sprintf("denied: program %q is not in the allowlist (%s)",
 		baseName, lr.Path)
+	return deny
+}
+
+// digest returns the SHA-256 digest of the resolved executable.
+func (lr *LoadResult) digest(resolved string) (string, error) {
+	if resolved == "" {
+		return "", errors.New("not resolved")
+	}
+	if lr.hashFile != nil {
+		return lr.hashFile(resolved)
+	}
+	return HashFile(resolved)
+}
+
+// HashFile returns the hex SHA-256 digest of the file at path, in the form
+// Rule.SHA256 lists it.
+func HashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// checkScheme verifies that URIs with the given scheme may be opened with
+// verb. With an allowlist loaded, only the schemes it lists are allowed,
+// with the [policy] verbs.
+func (lr *LoadResult) checkScheme(scheme, verb string) error {
+	if !lr.Loaded {
+		return nil
+	}
+	for _, s := range lr.List.Schemes {
+		if !strings.EqualFold(strings.TrimSuffix(s, ":"), scheme) {
+			continue
+		}
+		if (Rule{}).allowsVerb(verb, lr.List.Policy) {
+			return nil
+		}
+		return &DenyError{
+			Category: protocol.ErrDeniedAllowlist,
+			Program:  scheme + ":",
+			Rule:     fmt.Sprintf("[policy] verbs [%s]", strings.Join(lr.List.Policy.DefaultVerbs(), ", ")),
+			Path:     lr.Path,
+			msg: fmt.Sprintf("denied: URIs may not be opened with verb %q (allowed: %s)",
+				verb, strings.Join(lr.List.Policy.DefaultVerbs(), ", ")),
+		}
+	}
+	return &DenyError{
+		Category: protocol.ErrDeniedAllowlist,
+		Program:  scheme + ":",
+		Rule:     fmt.Sprintf("schemes [%s]", strings.Join(lr.List.Schemes, ", ")),
+		Path:     lr.Path,
+		msg:      fmt.Sprintf("denied: URI scheme %q is not in the allowlist (%s)", scheme, lr.Path),
+	}
+}
+
+// String formats the rule the way check-config prints it, e.g.
+// "p4 [edit, sync]", "notepad (any args)" or
+// "p4 at C:\Program Files\Perforce\p4.exe [sync]".
+func (r Rule) String() string {
+	name := r.Program
+	if name == "" {
+		name = normalizeProgram(r.Path)
+	}
+	if r.Path != "" {
+		name += " at " + r.Path
+	}
+	if r.Dir != "" {
+		name += " in " + r.Dir
+	}
+	if len(r.SHA256) > 0 {
+		name += " (sha256 pinned)"
+	}
+	if len(r.Verbs) > 0 {
+		name += " (verbs: " + strings.Join(r.Verbs, ", ") + ")"
+	}
+	if len(r.Commands) == 0 {
+		return name + " (any args)"
+	}
+	return fmt.Sprintf("%s [%s]", name, strings.Join(r.Commands, ", "))
+}
+
+// matchesName reports whether the rule applies to the normalized program
+// name: its Program, or the base name of its Path. A rule with neither
+// but a Dir applies to every program in that directory.
+func (r Rule) matchesName(baseName string) bool {
+	switch {
+	case r.Program != "":
+		return matchProgram(baseName, r.Program)
+	case r.Path != "":
+		ok, err := path.Match(normalizeProgram(r.Path), baseName)
+		return ok && err == nil
+	default:
+		return r.Dir != ""
+	}
+}
+
+// matchesDigest reports whether digest is one of the rule's pinned hashes.
+func (r Rule) matchesDigest(digest string) bool {
+	for _, pinned := range r.SHA256 {
+		if strings.EqualFold(strings.TrimSpace(pinned), digest) {
+			return true
+		}
+	}
+	return false
+}
+
+// verbs returns the verbs the rule allows: its own, or the policy's.
+func (r Rule) verbs(policy Policy) []string {
+	if len(r.Verbs) > 0 {
+		return r.Verbs
+	}
+	return policy.DefaultVerbs()
+}
+
+// allowsVerb reports whether the rule permits the normalized verb. With
+// elevation "any", runas is allowed wherever the rule applies; otherwise
+// only if the rule's own verbs list it, never through the [policy] verbs.
+func (r Rule) allowsVerb(verb string, policy Policy) bool {
+	verbs := r.verbs(policy)
+	if verb == VerbRunas {
+		if policy.Elevation == ElevationAny {
+			return true
+		}
+		verbs = r.Verbs
+	}
+	for _, allowed := range verbs {
+		if normalizeVerb(allowed) == verb {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeVerb lowercases a ShellExecuteEx verb; "" means "open".
+func normalizeVerb(verb string) string {
+	verb = strings.ToLower(strings.TrimSpace(verb))
+	if verb == "" {
+		return "open"
+	}
+	return verb
+}
+
+// DefaultVerbs returns the verbs allowed by rules without their own list.
+func (p Policy) DefaultVerbs() []string {
+	if len(p.Verbs) > 0 {
+		return p.Verbs
+	}
+	return []string{"open"}
+}
+
+// ElevationSummary describes the elevation setting for check-config.
+func (p Policy) ElevationSummary() string {
+	switch p.Elevation {
+	case ElevationDeny:
+		return ElevationDeny + " (runas is never allowed)"
+	case ElevationAny:
+		return ElevationAny + " (runas is allowed for every allowed program)"
+	default:
+		return ElevationAllowlisted + " (runas only where a rule's verbs list it)"
+	}
+}
+
+// matchesLocation reports whether the resolved executable satisfies the
+// rule's Path and Dir. Rules without either match any location.
+func (r Rule) matchesLocation(resolved string) bool {
+	if r.Path != "" && !matchPath(r.Path, resolved) {
+		return false
+	}
+	if r.Dir != "" && !inDir(r.Dir, resolved) {
+		return false
+	}
+	return true
+}
+
+// ProgramName returns the name rules and per-program config sections are
+// matched against: the base name of file, lowercased, without .exe. It
+// returns "" for URIs, which no program rule matches.
+func ProgramName(file string) string {
+	if pathconv.URIScheme(file) != "" {
+		return ""
+	}
+	return normalizeProgram(file)
 }
 
 // normalizeProgram extracts the base filename, lowercased, without .exe extension.
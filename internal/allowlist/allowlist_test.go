@@ -1,9 +1,13 @@
 package allowlist
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+
+	"github.com/sverrirab/wsl-host-start/internal/protocol"
 )
 
 func TestCheckNoAllowlist(t *testing.T) {
@@ -183,6 +187,37 @@ program = "notepad.exe"
 	}
 }
 
+func TestCheckRequestRawCmdLine(t *testing.T) {
+	lr := &LoadResult{
+		Loaded: true,
+		Path:   `C:\wstart\allowlist.toml`,
+		List: &List{
+			Allow: []Rule{
+				{Program: "p4", Commands: []string{"edit", "sync"}},
+				{Program: "setup"},
+			},
+		},
+	}
+
+	// Split with MSVCRT rules this starts with "edit", but a program that
+	// splits differently may see "obliterate" first.
+	err := lr.CheckRequest(Request{File: "p4", CmdLine: `"edit" obliterate //depot/...`})
+	var deny *DenyError
+	if !errors.As(err, &deny) || deny.Category != protocol.ErrDeniedAllowlist {
+		t.Fatalf("raw command line for p4: err = %v, want a denied-by-allowlist DenyError", err)
+	}
+	if deny.Rule != "p4 [edit, sync]" || !strings.Contains(deny.Error(), "raw command line") {
+		t.Errorf("DenyError = %+v, %q", deny, deny.Error())
+	}
+	if err := lr.CheckRequest(Request{File: "p4", CmdLine: "edit a.c"}); err == nil {
+		t.Error("raw command line for p4 edit should be denied")
+	}
+
+	if err := lr.CheckRequest(Request{File: "setup.exe", CmdLine: `/S /D=C:\Program Files\Tool`}); err != nil {
+		t.Errorf("raw command line for a rule without commands: %v", err)
+	}
+}
+
 func TestCheckEmptyAllowlistDeniesAll(t *testing.T) {
 	// An allowlist file that exists but has no rules should deny everything.
 	dir := t.TempDir()
@@ -382,3 +417,426 @@ func TestLoadMalformed(t *testing.T) {
 		t.Fatal("expected error for malformed allowlist file")
 	}
 }
+
+func TestCheckDenyErrorDetails(t *testing.T) {
+	lr := &LoadResult{
+		Loaded: true,
+		Path:   `C:\Program Files\wstart\allowlist.toml`,
+		List: &List{
+			Allow: []Rule{{Program: "p4", Commands: []string{"edit", "sync"}}},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		file     string
+		args     []string
+		category string
+		rule     string
+		path     string
+	}{
+		{"deny list", `C:\Windows\System32\cmd.exe`, nil, protocol.ErrDeniedDenylist, "cmd", ""},
+		{"subcommand", "p4", []string{"obliterate"}, protocol.ErrDeniedAllowlist, "p4 [edit, sync]", lr.Path},
+		{"not listed", "git", nil, protocol.ErrDeniedAllowlist, "", lr.Path},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var deny *DenyError
+			if err := lr.Check(tt.file, tt.args); !errors.As(err, &deny) {
+				t.Fatalf("Check = %v, want *DenyError", err)
+			}
+			if deny.Category != tt.category || deny.Rule != tt.rule || deny.Path != tt.path {
+				t.Errorf("DenyError = %+v, want category=%q rule=%q path=%q", deny, tt.category, tt.rule, tt.path)
+			}
+		})
+	}
+}
+
+func TestCheckSchemes(t *testing.T) {
+	dir := t.TempDir()
+	data := "schemes = [\"https\", \"mailto:\"]\n\n[[allow]]\nprogram = \"notepad\"\n"
+	if err := os.WriteFile(filepath.Join(dir, AllowlistFile), []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	lr, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		file    string
+		wantErr bool
+	}{
+		{"https://example.com", false},
+		{"HTTPS://EXAMPLE.COM/cmd", false},
+		{"mailto:a@b.com", false},
+		{"http://example.com", true},
+		{"ms-settings:display", true},
+		{`C:\Windows\notepad.exe`, false},
+	}
+	for _, tt := range tests {
+		err := lr.Check(tt.file, nil)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Check(%q): err=%v, wantErr=%v", tt.file, err, tt.wantErr)
+		}
+	}
+
+	var deny *DenyError
+	if err := lr.Check("ms-settings:display", nil); !errors.As(err, &deny) || deny.Category != protocol.ErrDeniedAllowlist || deny.Rule != "schemes [https, mailto:]" {
+		t.Errorf("Check(ms-settings:) = %v, want allowlist denial naming the schemes", err)
+	}
+
+	if err := (&LoadResult{}).Check("ms-settings:display", nil); err != nil {
+		t.Errorf("no allowlist should allow every scheme, got: %v", err)
+	}
+}
+
+func TestCheckRequestResolvedPath(t *testing.T) {
+	lr := &LoadResult{
+		Loaded: true,
+		Path:   `C:\Program Files\wstart\allowlist.toml`,
+		List: &List{
+			Allow: []Rule{
+				{Program: "notepad", Dir: `C:\Windows\*`},
+				{Path: `C:\Program Files\Perforce\p4.exe`, Commands: []string{"sync"}},
+				{Program: "code"},
+			},
+		},
+	}
+
+	tests := []struct {
+		file     string
+		resolved string
+		args     []string
+		wantErr  bool
+	}{
+		{"notepad", `C:\Windows\System32\notepad.exe`, nil, false},
+		{"notepad", `C:\Users\me\Downloads\notepad.exe`, nil, true},
+		{"notepad", "", nil, true},
+		{"p4", `C:\Program Files\Perforce\p4.exe`, []string{"sync"}, false},
+		{"P4.EXE", `c:\program files\perforce\P4.EXE`, []string{"sync"}, false},
+		{"p4", `C:\Program Files\Perforce\p4.exe`, []string{"obliterate"}, true},
+		{"p4", `C:\tools\p4.exe`, []string{"sync"}, true},
+		{"code", `C:\Users\me\AppData\Local\Programs\Microsoft VS Code\bin\code.cmd`, nil, false},
+		{"code", "", nil, false},
+		{"evil", `C:\Windows\System32\cmd.exe`, nil, true},
+	}
+	for _, tt := range tests {
+		err := lr.CheckRequest(Request{File: tt.file, Resolved: tt.resolved, Args: tt.args})
+		if (err != nil) != tt.wantErr {
+			t.Errorf("CheckRequest(%q, %q, %v): err=%v, wantErr=%v", tt.file, tt.resolved, tt.args, err, tt.wantErr)
+		}
+	}
+
+	var deny *DenyError
+	err := lr.CheckRequest(Request{File: "notepad", Resolved: `C:\Users\me\Downloads\notepad.exe`})
+	if !errors.As(err, &deny) || deny.Rule != `notepad in C:\Windows\* (any args)` {
+		t.Errorf("CheckRequest(misplaced notepad) = %v, want denial naming the dir rule", err)
+	}
+	err = lr.CheckRequest(Request{File: "evil", Resolved: `C:\Windows\System32\cmd.exe`})
+	if !errors.As(err, &deny) || deny.Category != protocol.ErrDeniedDenylist {
+		t.Errorf("CheckRequest(resolves to cmd.exe) = %v, want deny list denial", err)
+	}
+}
+
+func TestCheckRequestSHA256(t *testing.T) {
+	exe := filepath.Join(t.TempDir(), "p4.exe")
+	if err := os.WriteFile(exe, []byte("p4 build 1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	digest, err := HashFile(exe)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lr := &LoadResult{
+		Loaded: true,
+		List: &List{
+			Allow: []Rule{
+				{Program: "p4", SHA256: []string{strings.ToUpper(digest)}},
+				{Program: "code", SHA256: []string{"00"}},
+			},
+		},
+	}
+
+	if err := lr.CheckRequest(Request{File: "p4", Resolved: exe}); err != nil {
+		t.Errorf("pinned binary should be allowed, got: %v", err)
+	}
+
+	if err := os.WriteFile(exe, []byte("p4 build 2"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var deny *DenyError
+	err = lr.CheckRequest(Request{File: "p4", Resolved: exe})
+	if !errors.As(err, &deny) || deny.Category != protocol.ErrHashMismatch {
+		t.Fatalf("replaced binary: got %v, want hash mismatch", err)
+	}
+	if deny.Rule != "p4 (sha256 pinned) (any args)" {
+		t.Errorf("Rule = %q", deny.Rule)
+	}
+
+	for _, req := range []Request{
+		{File: "code"},
+		{File: "code", Resolved: filepath.Join(t.TempDir(), "missing.exe")},
+	} {
+		err := lr.CheckRequest(req)
+		if !errors.As(err, &deny) || deny.Category != protocol.ErrHashMismatch {
+			t.Errorf("CheckRequest(%+v) = %v, want hash mismatch", req, err)
+		}
+	}
+}
+
+func TestCheckRequestSHA256HashesOnce(t *testing.T) {
+	calls := 0
+	lr := &LoadResult{
+		Loaded: true,
+		List: &List{
+			Allow: []Rule{
+				{Program: "p4", SHA256: []string{"aa"}, Commands: []string{"sync"}},
+				{Program: "p4", SHA256: []string{"aa"}, Commands: []string{"info"}},
+			},
+		},
+		hashFile: func(string) (string, error) { calls++; return "aa", nil },
+	}
+	if err := lr.CheckRequest(Request{File: "p4", Resolved: `C:\p4.exe`, Args: []string{"info"}}); err != nil {
+		t.Fatalf("CheckRequest: %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("hashed %d times, want 1", calls)
+	}
+}
+
+func TestCheckRequestVerbs(t *testing.T) {
+	rules := []Rule{
+		{Program: "notepad"},
+		{Program: "explorer", Verbs: []string{"open", "Explore"}},
+		{Program: "regedit", Verbs: []string{"runas"}},
+	}
+	tests := []struct {
+		elevation string
+		file      string
+		verb      string
+		wantErr   bool
+	}{
+		{"", "notepad", "", false},
+		{"", "notepad", "OPEN", false},
+		{"", "notepad", "edit", true},
+		{"", "notepad", "runas", true},
+		{"", "explorer", "explore", false},
+		{"", "explorer", "properties", true},
+		{"", "regedit", "runas", false},
+		{"", "regedit", "open", true},
+		{ElevationAny, "notepad", "runas", false},
+		{ElevationAny, "notepad", "print", true},
+		{ElevationAny, "calc", "runas", true},
+		{ElevationDeny, "regedit", "runas", true},
+		{ElevationDeny, "notepad", "open", false},
+	}
+	for _, tt := range tests {
+		lr := &LoadResult{
+			Loaded: true,
+			List:   &List{Policy: Policy{Elevation: tt.elevation}, Allow: rules},
+		}
+		err := lr.CheckRequest(Request{File: tt.file, Verb: tt.verb})
+		if (err != nil) != tt.wantErr {
+			t.Errorf("elevation %q: CheckRequest(%q, verb %q): err=%v, wantErr=%v",
+				tt.elevation, tt.file, tt.verb, err, tt.wantErr)
+		}
+	}
+
+	if err := (&LoadResult{}).CheckRequest(Request{File: "notepad", Verb: "runas"}); err != nil {
+		t.Errorf("no allowlist should allow every verb, got: %v", err)
+	}
+}
+
+func TestCheckRequestVerbDenial(t *testing.T) {
+	lr := &LoadResult{
+		Loaded: true,
+		Path:   "allowlist.toml",
+		List: &List{
+			Policy: Policy{Verbs: []string{"open", "edit"}},
+			Allow:  []Rule{{Program: "notepad"}, {Program: "p4", Verbs: []string{"open"}, Commands: []string{"sync"}}},
+		},
+	}
+	if err := lr.CheckRequest(Request{File: "notepad", Verb: "edit"}); err != nil {
+		t.Errorf("policy verbs should apply to rules without verbs, got: %v", err)
+	}
+
+	var deny *DenyError
+	err := lr.CheckRequest(Request{File: "p4", Verb: "edit", Args: []string{"sync"}})
+	if !errors.As(err, &deny) || deny.Category != protocol.ErrDeniedAllowlist || deny.Rule != "p4 (verbs: open) [sync]" {
+		t.Fatalf("CheckRequest(p4 edit) = %v, want denial naming the p4 rule", err)
+	}
+	if !strings.Contains(deny.Error(), `verb "edit" (allowed: open)`) {
+		t.Errorf("message = %q", deny.Error())
+	}
+
+	lr.List.Policy.Elevation = ElevationDeny
+	err = lr.CheckRequest(Request{File: "notepad", Verb: "RunAs"})
+	if !errors.As(err, &deny) || deny.Rule != `[policy] elevation = "deny"` {
+		t.Errorf("CheckRequest(runas) = %v, want denial naming the elevation policy", err)
+	}
+}
+
+func TestLoadPolicy(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, AllowlistFile)
+
+	if err := os.WriteFile(path, []byte("[[allow]]\nprogram = \"notepad\"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	lr, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lr.List.Policy.Elevation != ElevationAllowlisted {
+		t.Errorf("default elevation = %q, want %q", lr.List.Policy.Elevation, ElevationAllowlisted)
+	}
+
+	content := "[policy]\nelevation = \"any\"\nverbs = [\"open\", \"print\"]\n\n[[allow]]\nprogram = \"notepad\"\nverbs = [\"edit\"]\n"
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if lr, err = Load(dir); err != nil {
+		t.Fatal(err)
+	}
+	if lr.List.Policy.Elevation != ElevationAny || len(lr.List.Policy.Verbs) != 2 || len(lr.List.Allow[0].Verbs) != 1 {
+		t.Errorf("Load = %+v", lr.List)
+	}
+
+	if err := os.WriteFile(path, []byte("[policy]\nelevation = \"sometimes\"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(dir); err == nil {
+		t.Error("expected error for an unknown elevation setting")
+	}
+}
+
+func TestCheckFileURIs(t *testing.T) {
+	lr := &LoadResult{
+		Loaded: true,
+		List: &List{
+			Schemes: []string{"file", "https"},
+			Allow:   []Rule{{Program: "notepad", Dir: `C:\Windows\*`}},
+		},
+	}
+	tests := []struct {
+		uri     string
+		wantErr bool
+	}{
+		{"file:///C:/Windows/System32/cmd.exe", true},
+		{"FILE:///c:/windows/system32/CMD.EXE", true},
+		{"file:///C:/Windows/System32/cm%64.exe", true},
+		{"file:///C:/Windows/System32/WindowsPowerShell/v1.0/powershell.exe", true},
+		{"file://server/share/pwsh.exe?x=1", true},
+		{"file:///home/me/cmd.exe", true},
+		{"file:///C:/Windows/System32/notepad.exe", false},
+		{"file:///C:/Users/me/notepad.exe", true},
+		{"file:///C:/Users/me/report.pdf", true},
+	}
+	for _, tt := range tests {
+		err := lr.Check(tt.uri, nil)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Check(%q): err=%v, wantErr=%v", tt.uri, err, tt.wantErr)
+		}
+	}
+
+	// The deny list applies even without an allowlist.
+	for _, uri := range []string{
+		"file:///C:/Windows/System32/cmd.exe",
+		"file:///C:/Windows/System32/WindowsPowerShell/v1.0/powershell.exe",
+	} {
+		var deny *DenyError
+		err := (&LoadResult{}).Check(uri, nil)
+		if !errors.As(err, &deny) || deny.Category != protocol.ErrDeniedDenylist {
+			t.Errorf("no allowlist: Check(%q) = %v, want deny list denial", uri, err)
+		}
+	}
+	if err := (&LoadResult{}).Check("file:///C:/Users/me/report.pdf", nil); err != nil {
+		t.Errorf("no allowlist should allow file URIs of other files, got: %v", err)
+	}
+}
+
+func TestCheckSchemeVerbs(t *testing.T) {
+	lr := &LoadResult{
+		Loaded: true,
+		List:   &List{Schemes: []string{"https"}},
+	}
+	tests := []struct {
+		elevation string
+		verb      string
+		wantErr   bool
+	}{
+		{"", "", false},
+		{"", "open", false},
+		{"", "print", true},
+		{"", "runas", true},
+		{ElevationDeny, "runas", true},
+		{ElevationAny, "runas", false},
+	}
+	for _, tt := range tests {
+		lr.List.Policy.Elevation = tt.elevation
+		err := lr.CheckRequest(Request{File: "https://x", Verb: tt.verb})
+		if (err != nil) != tt.wantErr {
+			t.Errorf("elevation %q: CheckRequest(https://x, verb %q): err=%v, wantErr=%v",
+				tt.elevation, tt.verb, err, tt.wantErr)
+		}
+	}
+
+	lr.List.Policy.Elevation = ElevationDeny
+	var deny *DenyError
+	err := lr.CheckRequest(Request{File: "file:///C:/Windows/notepad.exe", Verb: "runas"})
+	if !errors.As(err, &deny) || deny.Rule != `[policy] elevation = "deny"` {
+		t.Errorf("CheckRequest(file URI, runas) = %v, want denial naming the elevation policy", err)
+	}
+}
+
+func TestPolicyVerbsNeverElevate(t *testing.T) {
+	lr := &LoadResult{
+		Loaded: true,
+		List: &List{
+			Policy: Policy{Elevation: ElevationAllowlisted, Verbs: []string{"open", "runas"}},
+			Allow:  []Rule{{Program: "notepad"}},
+		},
+	}
+	if err := lr.CheckRequest(Request{File: "notepad", Verb: "runas"}); err == nil {
+		t.Error("runas from [policy] verbs should not elevate a rule without its own verbs")
+	}
+
+	dir := t.TempDir()
+	content := "[policy]\nverbs = [\"open\", \"RunAs\"]\n"
+	if err := os.WriteFile(filepath.Join(dir, AllowlistFile), []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(dir); err == nil {
+		t.Error("expected error for runas in [policy] verbs")
+	}
+}
+
+func TestCheckRequestRelativeResolved(t *testing.T) {
+	calls := 0
+	lr := &LoadResult{
+		Loaded: true,
+		List: &List{
+			Allow: []Rule{
+				{Program: "p4", SHA256: []string{"aa"}},
+				{Program: "notepad", Dir: `C:\Windows\*`},
+				{Program: "code"},
+			},
+		},
+		hashFile: func(string) (string, error) { calls++; return "aa", nil },
+	}
+	var deny *DenyError
+	err := lr.CheckRequest(Request{File: "p4", Resolved: "p4"})
+	if !errors.As(err, &deny) || deny.Category != protocol.ErrHashMismatch {
+		t.Errorf("CheckRequest(p4, relative) = %v, want hash mismatch", err)
+	}
+	if calls != 0 {
+		t.Errorf("hashed a relative path %d times", calls)
+	}
+	if err := lr.CheckRequest(Request{File: "notepad", Resolved: `Windows\notepad.exe`}); err == nil {
+		t.Error("dir rule should deny a relative resolved path")
+	}
+	if err := lr.CheckRequest(Request{File: "code", Resolved: "code"}); err != nil {
+		t.Errorf("unconstrained rule should allow an unresolved program, got: %v", err)
+	}
+}
@@ -0,0 +1,67 @@
+package allowlist
+
+import (
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// winPath normalizes an absolute Windows path for matching: lowercased
+// (NTFS compares names case-insensitively), with forward slashes and "."
+// and ".." elements resolved, so that path.Match can compare it. It
+// returns false for relative paths, which never match a path rule.
+func winPath(p string) (string, bool) {
+	p = strings.TrimPrefix(p, `\\?\`)
+	p = strings.ToLower(strings.ReplaceAll(p, `\`, "/"))
+	isDrive := len(p) >= 3 && p[1] == ':' && p[2] == '/' && p[0] >= 'a' && p[0] <= 'z'
+	isUNC := strings.HasPrefix(p, "//") && len(p) > 2 && p[2] != '/'
+	if !isDrive && !isUNC {
+		return "", false
+	}
+	return path.Clean(p), true
+}
+
+// isAbs reports whether p is an absolute Windows path, or an absolute path
+// on the OS the check runs on.
+func isAbs(p string) bool {
+	_, ok := winPath(p)
+	return ok || filepath.IsAbs(p)
+}
+
+// matchPath reports whether the executable file is the one pattern names.
+// pattern is an absolute Windows path and may contain glob metacharacters
+// ("C:\Program Files\Perforce\p4*.exe").
+func matchPath(pattern, file string) bool {
+	pat, ok := winPath(pattern)
+	if !ok {
+		return false
+	}
+	f, ok := winPath(file)
+	if !ok {
+		return false
+	}
+	matched, err := path.Match(pat, f)
+	return matched && err == nil
+}
+
+// inDir reports whether the executable file lies in a directory matching
+// pattern, or anywhere below one. pattern is an absolute Windows path and
+// may contain glob metacharacters ("C:\Program Files\*").
+func inDir(pattern, file string) bool {
+	pat, ok := winPath(pattern)
+	if !ok {
+		return false
+	}
+	f, ok := winPath(file)
+	if !ok {
+		return false
+	}
+	for dir := path.Dir(f); ; dir = path.Dir(dir) {
+		if matched, err := path.Match(pat, dir); matched && err == nil {
+			return true
+		}
+		if parent := path.Dir(dir); parent == dir {
+			return false
+		}
+	}
+}
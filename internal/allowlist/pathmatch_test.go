@@ -0,0 +1,53 @@
+package allowlist
+
+import "testing"
+
+func TestMatchPath(t *testing.T) {
+	tests := []struct {
+		pattern string
+		file    string
+		want    bool
+	}{
+		{`C:\Program Files\Perforce\p4.exe`, `C:\Program Files\Perforce\p4.exe`, true},
+		{`C:\Program Files\Perforce\p4.exe`, `c:\PROGRAM FILES\perforce\P4.EXE`, true},
+		{`C:\Program Files\Perforce\p4.exe`, `\\?\C:\Program Files\Perforce\p4.exe`, true},
+		{`C:\Program Files\Perforce\p4.exe`, `C:\Program Files\Perforce\..\Perforce\p4.exe`, true},
+		{`C:\Program Files\Perforce\p4*.exe`, `C:\Program Files\Perforce\p4v.exe`, true},
+		{`C:\Program Files\Perforce\p4.exe`, `C:\Users\me\Downloads\p4.exe`, false},
+		{`C:\Program Files\Perforce\p4.exe`, `C:\Program Files\Perforce\p4.exe\..\..\evil\p4.exe`, false},
+		{`C:\Program Files\Perforce\p4.exe`, `p4.exe`, false},
+		{`C:\Program Files\Perforce\p4.exe`, `Perforce\p4.exe`, false},
+		{`p4.exe`, `p4.exe`, false},
+		{`\\server\tools\p4.exe`, `\\SERVER\Tools\p4.exe`, true},
+		{`\\server\tools\p4.exe`, `\server\tools\p4.exe`, false},
+	}
+	for _, tt := range tests {
+		if got := matchPath(tt.pattern, tt.file); got != tt.want {
+			t.Errorf("matchPath(%q, %q) = %v, want %v", tt.pattern, tt.file, got, tt.want)
+		}
+	}
+}
+
+func TestInDir(t *testing.T) {
+	tests := []struct {
+		pattern string
+		file    string
+		want    bool
+	}{
+		{`C:\Program Files\*`, `C:\Program Files\Perforce\p4.exe`, true},
+		{`C:\Program Files\*`, `C:\Program Files\Microsoft VS Code\bin\code.cmd`, true},
+		{`C:\Program Files\*`, `C:\Program Files\tool.exe`, false},
+		{`C:\Program Files`, `C:\Program Files\tool.exe`, true},
+		{`C:\Program Files`, `C:\Program Files (x86)\tool.exe`, false},
+		{`C:\Program Files\*`, `C:\Program Files (x86)\Tool\tool.exe`, false},
+		{`C:\Program Files\*`, `C:\Users\me\Downloads\notepad.exe`, false},
+		{`C:\Program Files\*`, `C:\Program Files\..\Users\me\notepad.exe`, false},
+		{`C:\Windows\System32`, `c:\windows\system32\notepad.exe`, true},
+		{`C:\Windows\System32`, `notepad.exe`, false},
+	}
+	for _, tt := range tests {
+		if got := inDir(tt.pattern, tt.file); got != tt.want {
+			t.Errorf("inDir(%q, %q) = %v, want %v", tt.pattern, tt.file, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package allowlist
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// Pin adds digest to the SHA-256 hashes of every rule in the allowlist
+// file content data that names program, through its program or path, and
+// applies to it when it resolves to resolved. Hashes already listed are
+// kept, so earlier builds stay allowed until they are removed by hand.
+// Rules with only a dir are never pinned: they cover every program in the
+// directory. Pin returns the updated content and the number of rules
+// changed. The file is edited in place, so comments and layout are kept.
+func Pin(data []byte, program, resolved, digest string) ([]byte, int, error) {
+	var list List
+	if _, err := toml.Decode(string(data), &list); err != nil {
+		return nil, 0, err
+	}
+	baseName := normalizeProgram(program)
+	var indexes []int
+	var dirOnly, pinned bool
+	for i, rule := range list.Allow {
+		if !rule.matchesName(baseName) || !rule.matchesLocation(resolved) {
+			continue
+		}
+		switch {
+		case rule.Program == "" && rule.Path == "":
+			dirOnly = true
+		case rule.matchesDigest(digest):
+			pinned = true
+		default:
+			indexes = append(indexes, i)
+		}
+	}
+	if len(indexes) == 0 {
+		switch {
+		case pinned:
+			return data, 0, nil
+		case dirOnly:
+			return nil, 0, fmt.Errorf("only dir rules apply to %q at %s; add a program or path rule for it to pin its hash", baseName, resolved)
+		}
+		return nil, 0, fmt.Errorf("no [[allow]] rule applies to %q at %s", baseName, resolved)
+	}
+
+	eol := "\n"
+	if strings.Contains(string(data), "\r\n") {
+		eol = "\r\n"
+	}
+	lines := strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	headers, rules := tableHeaders(lines)
+	if len(rules) != len(list.Allow) {
+		return nil, 0, fmt.Errorf("cannot locate the [[allow]] tables (found %d of %d); add the hash by hand", len(rules), len(list.Allow))
+	}
+
+	// Edit from the end so earlier line numbers stay valid.
+	for _, i := range slices.Backward(indexes) {
+		entry := hashEntry(append(slices.Clip(list.Allow[i].SHA256), digest))
+		start, end := headers[rules[i]]+1, len(lines)
+		if rules[i]+1 < len(headers) {
+			end = headers[rules[i]+1]
+		}
+		var err error
+		if lines, err = setKey(lines, start, end, "sha256", entry); err != nil {
+			return nil, 0, fmt.Errorf("rule %d: %w", i+1, err)
+		}
+	}
+	out := []byte(strings.Join(lines, eol))
+
+	// The edit must leave exactly the intended rules pinned.
+	var check List
+	if _, err := toml.Decode(string(out), &check); err != nil {
+		return nil, 0, fmt.Errorf("pinned allowlist does not parse: %w", err)
+	}
+	for _, i := range indexes {
+		want := append(slices.Clip(list.Allow[i].SHA256), digest)
+		if i >= len(check.Allow) || !slices.Equal(check.Allow[i].SHA256, want) {
+			return nil, 0, fmt.Errorf("rule %d was not pinned; add the hash by hand", i+1)
+		}
+	}
+	return out, len(indexes), nil
+}
+
+// hashEntry formats the sha256 key of a rule.
+func hashEntry(digests []string) string {
+	quoted := make([]string, len(digests))
+	for i, d := range digests {
+		quoted[i] = fmt.Sprintf("%q", d)
+	}
+	return "sha256 = [" + strings.Join(quoted, ", ") + "]"
+}
+
+// tableHeaders returns the line numbers of all table headers, and the
+// index into them of each [[allow]] header. A table runs to the next
+// header or the end of the file.
+func tableHeaders(lines []string) (headers, rules []int) {
+	for n, line := range lines {
+		line, _, _ = strings.Cut(line, "#")
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "[") || !strings.HasSuffix(line, "]") {
+			continue
+		}
+		if line == "[[allow]]" {
+			rules = append(rules, len(headers))
+		}
+		headers = append(headers, n)
+	}
+	return headers, rules
+}
+
+// setKey replaces the single-line key = value assignment of key within
+// lines[start:end] with entry, or inserts entry after the last assignment
+// there if the key is not set.
+func setKey(lines []string, start, end int, key, entry string) ([]string, error) {
+	insert := start
+	for n := start; n < end; n++ {
+		trimmed := strings.TrimSpace(lines[n])
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		insert = n + 1
+		name, value, ok := strings.Cut(trimmed, "=")
+		if !ok || strings.TrimSpace(name) != key {
+			continue
+		}
+		if !strings.Contains(value, "]") {
+			return nil, fmt.Errorf("%s spans several lines; replace it by hand", key)
+		}
+		indent := lines[n][:len(lines[n])-len(strings.TrimLeft(lines[n], " \t"))]
+		lines[n] = indent + entry
+		return lines, nil
+	}
+	return slices.Insert(lines, insert, entry), nil
+}
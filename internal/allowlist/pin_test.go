@@ -0,0 +1,99 @@
+package allowlist
+
+import (
+	"strings"
+	"testing"
+)
+
+const pinDigest = "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+
+func TestPin(t *testing.T) {
+	in := `# Allowed programs
+schemes = ["https"]
+
+[[allow]]
+program = "notepad"
+
+[[allow]]
+program = "p4"
+commands = ["sync"] # read-only
+
+[policy]
+elevation = "deny"
+
+# Perforce admin
+[[allow]]
+path = 'C:\Program Files\Perforce\p4.exe'
+sha256 = ["00"]
+`
+	out, n, err := Pin([]byte(in), "p4", `C:\Program Files\Perforce\p4.exe`, pinDigest)
+	if err != nil {
+		t.Fatalf("Pin: %v", err)
+	}
+	if n != 2 {
+		t.Errorf("pinned %d rules, want 2", n)
+	}
+	want := strings.NewReplacer(
+		"commands = [\"sync\"] # read-only\n", "commands = [\"sync\"] # read-only\nsha256 = [\""+pinDigest+"\"]\n",
+		`sha256 = ["00"]`, `sha256 = ["00", "`+pinDigest+`"]`,
+	).Replace(in)
+	if string(out) != want {
+		t.Errorf("Pin output:\n%s\nwant:\n%s", out, want)
+	}
+}
+
+func TestPinKeepsCRLF(t *testing.T) {
+	in := "[[allow]]\r\nprogram = \"code\"\r\n"
+	out, _, err := Pin([]byte(in), "code", `C:\code.exe`, pinDigest)
+	if err != nil {
+		t.Fatalf("Pin: %v", err)
+	}
+	want := "[[allow]]\r\nprogram = \"code\"\r\nsha256 = [\"" + pinDigest + "\"]\r\n"
+	if string(out) != want {
+		t.Errorf("Pin = %q, want %q", out, want)
+	}
+}
+
+func TestPinErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+	}{
+		{"no rule", "[[allow]]\nprogram = \"code\"\n"},
+		{"wrong location", "[[allow]]\npath = 'D:\\p4.exe'\n"},
+		{"multi-line array", "[[allow]]\nprogram = \"p4\"\nsha256 = [\n  \"00\",\n]\n"},
+		{"inline tables", "allow = [{program = \"p4\"}]\n"},
+		{"malformed", "[[allow]\n"},
+	}
+	for _, tt := range tests {
+		if _, _, err := Pin([]byte(tt.in), "p4", `C:\p4.exe`, pinDigest); err == nil {
+			t.Errorf("%s: Pin succeeded, want error", tt.name)
+		}
+	}
+}
+
+func TestPinDirRules(t *testing.T) {
+	in := "[[allow]]\ndir = 'C:\\Tools'\n"
+	if _, _, err := Pin([]byte(in), "foo", `C:\Tools\foo.exe`, pinDigest); err == nil || !strings.Contains(err.Error(), "only dir rules") {
+		t.Errorf("Pin(dir-only rule) = %v, want a refusal", err)
+	}
+
+	// A program rule next to the dir rule is pinned; the dir rule is not.
+	in += "\n[[allow]]\nprogram = \"foo\"\n"
+	out, n, err := Pin([]byte(in), "foo", `C:\Tools\foo.exe`, pinDigest)
+	if err != nil || n != 1 {
+		t.Fatalf("Pin = %d, %v; want 1 rule", n, err)
+	}
+	want := in + "sha256 = [\"" + pinDigest + "\"]\n"
+	if string(out) != want {
+		t.Errorf("Pin output:\n%s\nwant:\n%s", out, want)
+	}
+}
+
+func TestPinAlreadyPinned(t *testing.T) {
+	in := "[[allow]]\nprogram = \"code\"\nsha256 = [\"" + strings.ToUpper(pinDigest) + "\"]\n"
+	out, n, err := Pin([]byte(in), "code", `C:\code.exe`, pinDigest)
+	if err != nil || n != 0 || string(out) != in {
+		t.Errorf("Pin = %q, %d, %v; want the input unchanged", out, n, err)
+	}
+}
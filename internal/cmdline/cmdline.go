@@ -0,0 +1,114 @@
+// Package cmdline assembles and splits Windows command lines.
+//
+// Windows passes a program its arguments as one string. Most programs
+// split it with CommandLineToArgvW or the MSVC runtime, which agree on
+// these rules:
+//
+//   - arguments are separated by spaces and tabs outside double quotes
+//   - 2n backslashes followed by a quote produce n backslashes, and the
+//     quote opens or closes a quoted section
+//   - 2n+1 backslashes followed by a quote produce n backslashes and a
+//     literal quote
+//   - backslashes not followed by a quote are literal
+//
+// Join quotes arguments so that they survive this split unchanged; Split
+// is the matching parser. Both are pure Go so the rules can be tested
+// outside Windows.
+package cmdline
+
+import "strings"
+
+// Quote returns arg quoted for a Windows command line. Arguments without
+// whitespace or quotes are returned unchanged.
+func Quote(arg string) string {
+	if arg != "" && !strings.ContainsAny(arg, " \t\n\v\"") {
+		return arg
+	}
+
+	var b strings.Builder
+	b.WriteByte('"')
+	slashes := 0
+	for i := 0; i < len(arg); i++ {
+		c := arg[i]
+		switch c {
+		case '\\':
+			slashes++
+		case '"':
+			// Escape the backslashes before the quote, then the quote.
+			b.WriteString(strings.Repeat(`\`, slashes+1))
+			slashes = 0
+		default:
+			slashes = 0
+		}
+		b.WriteByte(c)
+	}
+	// Trailing backslashes would escape the closing quote.
+	b.WriteString(strings.Repeat(`\`, slashes))
+	b.WriteByte('"')
+	return b.String()
+}
+
+// Join quotes each argument and joins them with spaces, producing the
+// lpParameters string of ShellExecuteEx.
+func Join(args []string) string {
+	quoted := make([]string, len(args))
+	for i, a := range args {
+		quoted[i] = Quote(a)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// Split parses a command line into arguments the way CommandLineToArgvW
+// parses everything after the program name. Inside a quoted section, ""
+// produces a literal quote and ends the section.
+func Split(cmdLine string) []string {
+	var args []string
+	for {
+		cmdLine = strings.TrimLeft(cmdLine, " \t")
+		if cmdLine == "" {
+			return args
+		}
+		var arg string
+		arg, cmdLine = nextArg(cmdLine)
+		args = append(args, arg)
+	}
+}
+
+// nextArg reads one argument from the start of s and returns it with the
+// unread rest of s.
+func nextArg(s string) (arg, rest string) {
+	var b strings.Builder
+	inQuote := false
+	slashes := 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch c {
+		case '\\':
+			slashes++
+			continue
+		case '"':
+			b.WriteString(strings.Repeat(`\`, slashes/2))
+			if slashes%2 == 1 {
+				b.WriteByte('"')
+			} else {
+				if inQuote && i+1 < len(s) && s[i+1] == '"' {
+					b.WriteByte('"')
+					i++
+				}
+				inQuote = !inQuote
+			}
+			slashes = 0
+			continue
+		case ' ', '\t':
+			if !inQuote {
+				b.WriteString(strings.Repeat(`\`, slashes))
+				return b.String(), s[i+1:]
+			}
+		}
+		b.WriteString(strings.Repeat(`\`, slashes))
+		slashes = 0
+		b.WriteByte(c)
+	}
+	b.WriteString(strings.Repeat(`\`, slashes))
+	return b.String(), ""
+}
@@ -0,0 +1,69 @@
+package cmdline
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestQuote(t *testing.T) {
+	tests := []struct {
+		arg  string
+		want string
+	}{
+		{"plain", "plain"},
+		{`C:\dir\file.txt`, `C:\dir\file.txt`},
+		{"", `""`},
+		{"two words", `"two words"`},
+		{`C:\Program Files\`, `"C:\Program Files\\"`},
+		{`say "hi"`, `"say \"hi\""`},
+		{`a\"b`, `"a\\\"b"`},
+		{`\\server\share\`, `\\server\share\`},
+		{"tab\there", "\"tab\there\""},
+	}
+	for _, tt := range tests {
+		if got := Quote(tt.arg); got != tt.want {
+			t.Errorf("Quote(%q) = %s, want %s", tt.arg, got, tt.want)
+		}
+	}
+}
+
+func TestSplit(t *testing.T) {
+	// Examples from the Microsoft documentation of argument parsing.
+	tests := []struct {
+		cmdLine string
+		want    []string
+	}{
+		{`"abc" d e`, []string{"abc", "d", "e"}},
+		{`a\\\b d"e f"g h`, []string{`a\\\b`, "de fg", "h"}},
+		{`a\\\"b c d`, []string{`a\"b`, "c", "d"}},
+		{`a\\\\"b c" d e`, []string{`a\\b c`, "d", "e"}},
+		// CommandLineToArgvW: "" inside quotes ends the quoted section.
+		{`a"b"" c d`, []string{`ab"`, "c", "d"}},
+		{"  spaced\t\targs  ", []string{"spaced", "args"}},
+		{`""`, []string{""}},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		if got := Split(tt.cmdLine); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Split(%s) = %q, want %q", tt.cmdLine, got, tt.want)
+		}
+	}
+}
+
+func TestJoinRoundTrip(t *testing.T) {
+	tests := [][]string{
+		{"plain", "args"},
+		{`C:\Program Files\App\`, "--out", `D:\with space\`},
+		{"", "empty", ""},
+		{`"quoted"`, `back\"slash`, `\\"`, `trailing\\`},
+		{"-m", `message with "quotes" and \ backslash`},
+		{"tab\tand\nnewline", "\v"},
+		{`\\wsl.localhost\Ubuntu\home\user`},
+	}
+	for _, args := range tests {
+		line := Join(args)
+		if got := Split(line); !reflect.DeepEqual(got, args) {
+			t.Errorf("Split(Join(%q)) = %q (line %s)", args, got, line)
+		}
+	}
+}
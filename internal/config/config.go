@@ -5,6 +5,9 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/BurntSushi/toml"
 )
@@ -15,6 +18,8 @@ const ConfigFile = "config.toml"
 type Config struct {
 	Drives   DrivesConfig   `toml:"drives"`
 	Env      EnvConfig      `toml:"env"`
+	Args     ArgsConfig     `toml:"args"`
+	Stdin    StdinConfig    `toml:"stdin"`
 	Defaults DefaultsConfig `toml:"defaults"`
 }
 
@@ -26,8 +31,63 @@ type DrivesConfig struct {
 }
 
 type EnvConfig struct {
+	// Variables to forward, with optional WSLENV-style flags: "NAME/p"
+	// translates a path, "NAME/l" a colon-separated list of paths.
 	Forward []string `toml:"forward"`
 	Block   []string `toml:"block"`
+	// Also forward the variables listed in $WSLENV, with their flags.
+	MergeWSLENV bool `toml:"merge_wslenv"`
+	// Accept variables given for a single launch (wstart -env, -env-from)
+	// that the forward list does not name. The block list still applies.
+	AllowAdHoc bool `toml:"allow_adhoc"`
+	// Per-program scopes, keyed by program name without .exe (e.g. "p4").
+	Programs map[string]EnvProgram `toml:"programs"`
+}
+
+type EnvProgram struct {
+	// Replaces the global forward list for this program.
+	Forward []string `toml:"forward"`
+	// Added to the global block list for this program.
+	Block []string `toml:"block"`
+}
+
+// ForProgram returns the lists that apply to a program, named as
+// allowlist.ProgramName does: those of its [env.programs] section if it
+// has one, otherwise the global lists. The global block list always
+// applies; $WSLENV is only merged into the global forward list.
+func (e EnvConfig) ForProgram(name string) EnvConfig {
+	for key, prog := range e.Programs {
+		if name == "" || !strings.EqualFold(strings.TrimSuffix(strings.ToLower(key), ".exe"), name) {
+			continue
+		}
+		return EnvConfig{
+			Forward:    prog.Forward,
+			Block:      append(slices.Clip(e.Block), prog.Block...),
+			AllowAdHoc: e.AllowAdHoc,
+		}
+	}
+	return EnvConfig{Forward: e.Forward, Block: e.Block, MergeWSLENV: e.MergeWSLENV, AllowAdHoc: e.AllowAdHoc}
+}
+
+type ArgsConfig struct {
+	// Translate program arguments that name existing WSL paths.
+	Translate bool `toml:"translate"`
+	// Per-program overrides, keyed by program name without .exe (e.g. "p4").
+	Programs map[string]ProgramArgs `toml:"programs"`
+}
+
+type ProgramArgs struct {
+	// 1-based argument positions that are always translated, existing or
+	// not (e.g. output files). When set, no other arguments are translated.
+	Paths []int `toml:"paths"`
+	// Never translate this program's arguments.
+	Raw bool `toml:"raw"`
+}
+
+type StdinConfig struct {
+	// Temp files written for "wstart -" are removed by later runs once
+	// they are older than this (e.g. "24h"). Zero keeps them.
+	MaxAge time.Duration `toml:"max_age"`
 }
 
 type DefaultsConfig struct {
@@ -67,6 +127,13 @@ func defaults() *Config {
 				"P4TICKETS",
 				"P4TRUST",
 			},
+			AllowAdHoc: true,
+		},
+		Args: ArgsConfig{
+			Translate: true,
+		},
+		Stdin: StdinConfig{
+			MaxAge: 24 * time.Hour,
 		},
 		Defaults: DefaultsConfig{
 			Verb: "open",
@@ -4,6 +4,7 @@
 package drivecache
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -46,11 +47,31 @@ func (c *Cache) Get(helperPath string) (*protocol.DrivesResponse, error) {
 	return c.Refresh(helperPath)
 }
 
+// Cached returns cached drive info without invoking the helper.
+// Returns an error if the cache is stale or missing.
+func (c *Cache) Cached() (*protocol.DrivesResponse, error) {
+	return c.load()
+}
+
+// Store writes drive info obtained elsewhere (e.g. from a combined helper
+// request) to the cache.
+func (c *Cache) Store(resp *protocol.DrivesResponse) error {
+	return c.save(resp)
+}
+
 // Refresh invokes the helper to enumerate drives and updates the cache.
 func (c *Cache) Refresh(helperPath string) (*protocol.DrivesResponse, error) {
-	cmd := exec.Command(helperPath, "--drives")
+	return c.RefreshContext(context.Background(), helperPath)
+}
+
+// RefreshContext is like Refresh but kills the helper if ctx is done first.
+func (c *Cache) RefreshContext(ctx context.Context, helperPath string) (*protocol.DrivesResponse, error) {
+	cmd := exec.CommandContext(ctx, helperPath, "--drives")
 	out, err := cmd.Output()
 	if err != nil {
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("running %s --drives: %w", helperPath, ctx.Err())
+		}
 		return nil, fmt.Errorf("running %s --drives: %w", helperPath, err)
 	}
 
@@ -103,10 +124,16 @@ func (c *Cache) save(resp *protocol.DrivesResponse) error {
 	return os.WriteFile(c.path, data, 0600)
 }
 
-func cachePath() string {
+// Dir returns the wstart cache directory ($XDG_CACHE_HOME/wstart or
+// ~/.cache/wstart).
+func Dir() string {
 	if xdg := os.Getenv("XDG_CACHE_HOME"); xdg != "" {
-		return filepath.Join(xdg, "wstart", "drives.json")
+		return filepath.Join(xdg, "wstart")
 	}
 	home, _ := os.UserHomeDir()
-	return filepath.Join(home, ".cache", "wstart", "drives.json")
+	return filepath.Join(home, ".cache", "wstart")
+}
+
+func cachePath() string {
+	return filepath.Join(Dir(), "drives.json")
 }
@@ -7,10 +7,11 @@ package elevate
 import (
 	"fmt"
 	"os"
-	"strings"
 	"unsafe"
 
 	"golang.org/x/sys/windows"
+
+	"github.com/sverrirab/wsl-host-start/internal/cmdline"
 )
 
 var (
@@ -53,7 +54,7 @@ func RunElevated(args []string) error {
 		return fmt.Errorf("finding executable path: %w", err)
 	}
 
-	argsStr := strings.Join(args, " ")
+	argsStr := cmdline.Join(args)
 
 	verbPtr, _ := windows.UTF16PtrFromString("runas")
 	exePtr, _ := windows.UTF16PtrFromString(exe)
@@ -0,0 +1,199 @@
+// Package envpolicy decides which environment variables may be forwarded
+// to Windows programs. Forward and block lists hold variable names or glob
+// patterns such as "P4*", "*_TOKEN" or "*PASSWD*", matched
+// case-insensitively. A block entry always wins over a forward entry, and
+// a small set of protected variables is never forwarded at all.
+package envpolicy
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// ProtectedRule is the Decision.Pattern reported for protected variables.
+const ProtectedRule = "protected"
+
+// protected lists variables that are never forwarded, regardless of
+// configuration: they change which programs Windows finds and how it runs
+// them, so forwarding them would bypass the allowlist.
+var protected = map[string]bool{
+	"PATH":           true,
+	"PATHEXT":        true,
+	"COMSPEC":        true,
+	"SYSTEMROOT":     true,
+	"SYSTEMDRIVE":    true,
+	"WINDIR":         true,
+	"PSMODULEPATH":   true,
+	"__COMPAT_LAYER": true,
+}
+
+// ProtectedNames returns the variables that are never forwarded.
+func ProtectedNames() []string {
+	names := make([]string, 0, len(protected))
+	for name := range protected {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// IsProtected reports whether name is a protected variable.
+func IsProtected(name string) bool {
+	return protected[strings.ToUpper(name)]
+}
+
+// Policy holds the forward and block lists of an [env] config section.
+// Forward entries may carry WSLENV-style flags ("P4CONFIG/p"), which are
+// ignored here.
+type Policy struct {
+	Forward []string
+	Block   []string
+	// AnyForward lets every variable that is neither blocked nor protected
+	// through. The host sets it when merge_wslenv lets $WSLENV on the WSL
+	// side extend the forward list.
+	AnyForward bool
+	// AdHoc names variables set for a single launch (wstart -env). They
+	// need no forward entry, but the block list still applies.
+	AdHoc []string
+}
+
+// Decision is the outcome of Policy.Decide for one variable.
+type Decision struct {
+	// Forward is true if the variable may be forwarded.
+	Forward bool
+	// Blocked is true if a block entry matched, whether or not a forward
+	// entry did.
+	Blocked bool
+	// Pattern is the block entry that matched if Blocked (ProtectedRule
+	// for protected variables), otherwise the forward entry that matched,
+	// or "" if none did.
+	Pattern string
+}
+
+// Decide reports whether name may be forwarded and which entry decided it.
+// Names that are empty or contain "=" are never forwarded.
+func (p Policy) Decide(name string) Decision {
+	if name == "" || strings.ContainsAny(name, "=\x00") {
+		return Decision{}
+	}
+	if IsProtected(name) {
+		return Decision{Blocked: true, Pattern: ProtectedRule}
+	}
+	if pattern, ok := Match(p.Block, name); ok {
+		return Decision{Blocked: true, Pattern: pattern}
+	}
+	if pattern, ok := Match(p.Forward, name); ok {
+		return Decision{Forward: true, Pattern: pattern}
+	}
+	if p.AnyForward || slices.ContainsFunc(p.AdHoc, func(a string) bool { return strings.EqualFold(a, name) }) {
+		return Decision{Forward: true}
+	}
+	return Decision{}
+}
+
+// secretPatterns match the names of variables whose values are shown
+// redacted.
+var secretPatterns = []string{
+	"*PASSWD*", "*PASSWORD*", "*SECRET*", "*TOKEN*", "*CREDENTIAL*",
+	"*TICKET*", "*APIKEY*", "*API_KEY*", "*_KEY", "*PRIVATE*",
+}
+
+// IsSecret reports whether the name of a variable suggests that its value
+// is a secret that should not be printed.
+func IsSecret(name string) bool {
+	_, ok := Match(secretPatterns, name)
+	return ok
+}
+
+// Redact returns a copy of vars with the values of secret variables (see
+// IsSecret) replaced by "***", or nil if vars is empty.
+func Redact(vars map[string]string) map[string]string {
+	if len(vars) == 0 {
+		return nil
+	}
+	out := make(map[string]string, len(vars))
+	for k, v := range vars {
+		if IsSecret(k) {
+			v = "***"
+		}
+		out[k] = v
+	}
+	return out
+}
+
+// Removal is a variable that Enforce dropped.
+type Removal struct {
+	Name string
+	// Pattern is the block entry (or ProtectedRule) that matched, or ""
+	// if the variable is not in the forward list.
+	Pattern string
+}
+
+// String describes the removal for the user, e.g.
+// "P4PASSWD (blocked by *PASSWD*)".
+func (r Removal) String() string {
+	switch r.Pattern {
+	case "":
+		return r.Name + " (not in the forward list)"
+	case ProtectedRule:
+		return r.Name + " (protected)"
+	default:
+		return fmt.Sprintf("%s (blocked by %s)", r.Name, Name(r.Pattern))
+	}
+}
+
+// Enforce applies the policy to variables received from an untrusted
+// sender. It returns the variables that may be forwarded, or nil if none
+// may, and the removed ones sorted by name.
+func (p Policy) Enforce(vars map[string]string) (map[string]string, []Removal) {
+	var kept map[string]string
+	var removed []Removal
+	for name, val := range vars {
+		d := p.Decide(name)
+		if !d.Forward {
+			removed = append(removed, Removal{Name: name, Pattern: d.Pattern})
+			continue
+		}
+		if kept == nil {
+			kept = make(map[string]string)
+		}
+		kept[name] = val
+	}
+	sort.Slice(removed, func(i, j int) bool { return removed[i].Name < removed[j].Name })
+	return kept, removed
+}
+
+// IsPattern reports whether entry contains glob metacharacters.
+func IsPattern(entry string) bool {
+	return strings.ContainsAny(Name(entry), "*?[")
+}
+
+// Name returns entry without its WSLENV-style flags.
+func Name(entry string) string {
+	name, _, _ := strings.Cut(entry, "/")
+	return name
+}
+
+// Match returns the first entry that matches name. Exact names are
+// preferred over patterns, so "P4CONFIG/p" decides P4CONFIG even if "P4*"
+// comes first. A malformed pattern only matches itself.
+func Match(entries []string, name string) (string, bool) {
+	for _, e := range entries {
+		if strings.EqualFold(Name(e), name) {
+			return e, true
+		}
+	}
+	upper := strings.ToUpper(name)
+	for _, e := range entries {
+		if !IsPattern(e) {
+			continue
+		}
+		if ok, err := path.Match(strings.ToUpper(Name(e)), upper); ok && err == nil {
+			return e, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,153 @@
+package envpolicy
+
+import "testing"
+
+func TestMatch(t *testing.T) {
+	entries := []string{"P4*", "*_TOKEN", "*PASSWD*", "P4CONFIG/p", "GOPATH", "[bad"}
+	tests := []struct {
+		name string
+		want string
+		ok   bool
+	}{
+		{"P4PORT", "P4*", true},
+		{"p4client", "P4*", true},
+		{"P4CONFIG", "P4CONFIG/p", true},
+		{"GITHUB_TOKEN", "*_TOKEN", true},
+		{"MY_PASSWD_FILE", "*PASSWD*", true},
+		{"gopath", "GOPATH", true},
+		{"[bad", "[bad", true},
+		{"HOME", "", false},
+		{"TOKEN", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := Match(entries, tt.name)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("Match(%q) = %q, %v; want %q, %v", tt.name, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestDecideBlockWins(t *testing.T) {
+	p := Policy{
+		Forward: []string{"P4*", "CI_*"},
+		Block:   []string{"*PASSWD*", "P4TICKETS", "*_TOKEN"},
+	}
+	tests := []struct {
+		name string
+		want Decision
+	}{
+		{"P4PORT", Decision{Forward: true, Pattern: "P4*"}},
+		{"P4PASSWD", Decision{Blocked: true, Pattern: "*PASSWD*"}},
+		{"p4tickets", Decision{Blocked: true, Pattern: "P4TICKETS"}},
+		{"CI_JOB_TOKEN", Decision{Blocked: true, Pattern: "*_TOKEN"}},
+		{"SECRET_TOKEN", Decision{Blocked: true, Pattern: "*_TOKEN"}},
+		{"HOME", Decision{}},
+	}
+	for _, tt := range tests {
+		if got := p.Decide(tt.name); got != tt.want {
+			t.Errorf("Decide(%q) = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDecideProtected(t *testing.T) {
+	p := Policy{Forward: []string{"*"}, AnyForward: true}
+	for _, name := range []string{"PATH", "Path", "PATHEXT", "ComSpec", "__COMPAT_LAYER"} {
+		if got, want := p.Decide(name), (Decision{Blocked: true, Pattern: ProtectedRule}); got != want {
+			t.Errorf("Decide(%q) = %+v, want %+v", name, got, want)
+		}
+	}
+	for _, name := range []string{"", "PATH=C:\\evil", "A=B"} {
+		if got := p.Decide(name); got.Forward {
+			t.Errorf("Decide(%q) = %+v, want not forwarded", name, got)
+		}
+	}
+}
+
+func TestEnforce(t *testing.T) {
+	p := Policy{
+		Forward: []string{"P4*", "EDITOR"},
+		Block:   []string{"*PASSWD*"},
+	}
+	kept, removed := p.Enforce(map[string]string{
+		"P4PORT":   "ssl:perforce:1666",
+		"EDITOR":   "notepad",
+		"P4PASSWD": "hunter2",
+		"PATH":     `C:\evil`,
+		"HOME":     "/home/me",
+	})
+	if len(kept) != 2 || kept["P4PORT"] != "ssl:perforce:1666" || kept["EDITOR"] != "notepad" {
+		t.Errorf("kept = %v", kept)
+	}
+	want := []string{
+		"HOME (not in the forward list)",
+		"P4PASSWD (blocked by *PASSWD*)",
+		"PATH (protected)",
+	}
+	if len(removed) != len(want) {
+		t.Fatalf("removed = %v, want %v", removed, want)
+	}
+	for i, r := range removed {
+		if r.String() != want[i] {
+			t.Errorf("removed[%d] = %q, want %q", i, r, want[i])
+		}
+	}
+}
+
+func TestEnforceAnyForward(t *testing.T) {
+	p := Policy{Block: []string{"*_TOKEN"}, AnyForward: true}
+	kept, removed := p.Enforce(map[string]string{"GOPATH": "/go", "CI_TOKEN": "x"})
+	if len(kept) != 1 || kept["GOPATH"] != "/go" {
+		t.Errorf("kept = %v, want only GOPATH", kept)
+	}
+	if len(removed) != 1 || removed[0].Name != "CI_TOKEN" {
+		t.Errorf("removed = %v, want CI_TOKEN", removed)
+	}
+	if kept, removed := p.Enforce(nil); kept != nil || removed != nil {
+		t.Errorf("Enforce(nil) = %v, %v", kept, removed)
+	}
+}
+
+func TestDecideAdHoc(t *testing.T) {
+	p := Policy{Forward: []string{"EDITOR"}, Block: []string{"*PASSWD*"}, AdHoc: []string{"DEBUG", "P4PASSWD", "PATH"}}
+	tests := []struct {
+		name string
+		want Decision
+	}{
+		{"DEBUG", Decision{Forward: true}},
+		{"debug", Decision{Forward: true}},
+		{"P4PASSWD", Decision{Blocked: true, Pattern: "*PASSWD*"}},
+		{"PATH", Decision{Blocked: true, Pattern: ProtectedRule}},
+		{"OTHER", Decision{}},
+	}
+	for _, tt := range tests {
+		if got := p.Decide(tt.name); got != tt.want {
+			t.Errorf("Decide(%q) = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRedact(t *testing.T) {
+	got := Redact(map[string]string{
+		"P4PORT":         "ssl:perforce:1666",
+		"GITHUB_TOKEN":   "ghp_x",
+		"DB_PASSWORD":    "hunter2",
+		"AWS_SECRET_KEY": "abc",
+		"KEYBOARD":       "us",
+	})
+	want := map[string]string{
+		"P4PORT":         "ssl:perforce:1666",
+		"GITHUB_TOKEN":   "***",
+		"DB_PASSWORD":    "***",
+		"AWS_SECRET_KEY": "***",
+		"KEYBOARD":       "us",
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("Redact()[%q] = %q, want %q", k, got[k], v)
+		}
+	}
+	if Redact(nil) != nil {
+		t.Error("Redact(nil) != nil")
+	}
+}
@@ -19,12 +19,28 @@ const defaultConfig = `# config.toml — wstart configuration.
 # Z = "\\\\server\\share"
 #
 # [env]
-# # Environment variables to forward to Windows processes
-# forward = ["P4PORT", "P4CLIENT", "P4USER", "P4CONFIG"]
+# # Environment variables to forward to Windows processes (names or glob
+# # patterns; /p translates a path value, /l a colon-separated path list)
+# forward = ["P4PORT", "P4CLIENT", "P4USER", "P4CONFIG/p"]
 #
-# # Variables that are NEVER forwarded (defaults shown)
+# # Also forward the variables listed in $WSLENV (default: false). The
+# # helper cannot see $WSLENV, so with this on it only applies the block
+# # list: any WSL process can then forward any variable that is not blocked
+# merge_wslenv = false
+#
+# # Variables that are NEVER forwarded, names or glob patterns; a block
+# # entry always wins (defaults shown)
 # block = ["P4PASSWD", "P4TICKETS", "P4TRUST"]
 #
+# # Accept variables given with wstart -env / -env-from for one launch,
+# # even if the forward list does not name them; the block list still
+# # applies (default: true)
+# allow_adhoc = true
+#
+# # Per-program scope: p4 gets this forward list instead of the global one
+# [env.programs.p4]
+# forward = ["P4*", "P4CONFIG/p"]
+#
 # [defaults]
 # verb = "open"
 # show = "normal"  # normal | min | max | hidden
@@ -38,11 +54,21 @@ const defaultAllowlist = `# allowlist.toml — Restrict which programs wstart ca
 # Program matching is case-insensitive, with or without .exe extension.
 # For example, "notepad" matches notepad.exe, Notepad.EXE, etc.
 #
+# URIs (https://..., mailto:...) are checked against the schemes list
+# instead. It must stay above the first table.
+#
+# A rule allows only the "open" verb unless it lists others in verbs.
+# [policy] elevation decides when runas may start a program elevated:
+# "deny" never, "allowlisted" (the default) only where a rule's verbs
+# list it, "any" for every allowed program.
+#
 # After editing, re-sign from an elevated PowerShell:
 #   wstart-host.exe --sign-config
 
 # --- Enabled by default ---
 
+schemes = ["http", "https", "mailto"]
+
 [[allow]]
 program = "notepad"
 
@@ -51,9 +77,24 @@ program = "explorer.exe"
 
 # --- Examples (uncomment to enable) ---
 #
+# [policy]
+# elevation = "deny"
+# verbs = ["open", "edit"]   # default for rules without their own verbs (not runas)
+#
 # [[allow]]
 # program = "code"
 #
+# # Open folders and show their properties
+# [[allow]]
+# program = "explorer.exe"
+# verbs = ["open", "explore", "properties"]
+#
+# # Pin a program to where it is installed; path and dir are checked
+# # against the executable the name resolves to
+# [[allow]]
+# program = "notepad"
+# dir = "C:\\Windows\\*"
+#
 # [[allow]]
 # program = "p4"
 # commands = [
@@ -73,4 +114,8 @@ program = "explorer.exe"
 #     # Login
 #     "login", "logout", "set",
 # ]
+# # Only the installed p4.exe, not one found elsewhere on PATH
+# path = "C:\\Program Files\\Perforce\\p4.exe"
+# # Only this build of p4.exe; run wstart-host.exe --pin p4 to fill it in
+# # sha256 = ["<hex digest>"]
 `
@@ -0,0 +1,47 @@
+package launch
+
+import (
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/sverrirab/wsl-host-start/internal/allowlist"
+)
+
+// translateArgs converts the program arguments that refer to WSL paths.
+// By default every argument naming an existing path is translated; an
+// [args.programs.<name>] entry can instead list the positions to translate
+// or turn translation off for that program.
+func (p *pipeline) translateArgs(target string, args []string) ([]string, error) {
+	if len(args) == 0 || p.opts.RawArgs {
+		return args, nil
+	}
+	prog, hasProg := p.cfg.Args.Programs[allowlist.ProgramName(target)]
+	if prog.Raw || (!p.cfg.Args.Translate && !hasProg) {
+		return args, nil
+	}
+
+	out := make([]string, len(args))
+	for i, arg := range args {
+		force := false
+		if len(prog.Paths) > 0 {
+			if !slices.Contains(prog.Paths, i+1) {
+				out[i] = arg
+				continue
+			}
+			force = true
+		}
+		translated, err := p.conv.TranslateArg(p.ctx, arg, force)
+		if err != nil {
+			if force {
+				return nil, fmt.Errorf("translating argument %d (%q): %w", i+1, arg, err)
+			}
+			if p.opts.Verbose {
+				fmt.Fprintf(os.Stderr, "Could not translate argument %q: %v (passing it unchanged)\n", arg, err)
+			}
+			translated = arg
+		}
+		out[i] = translated
+	}
+	return out, nil
+}
@@ -0,0 +1,74 @@
+package launch
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sverrirab/wsl-host-start/internal/config"
+	"github.com/sverrirab/wsl-host-start/internal/pathconv"
+)
+
+// fakeWslpath puts a wslpath on $PATH that maps /x/y to W:\x\y.
+func fakeWslpath(t *testing.T) {
+	t.Helper()
+	dir := t.TempDir()
+	script := "#!/bin/sh\nprintf 'W:%s\\n' \"$2\" | tr / '\\\\'\n"
+	if err := os.WriteFile(filepath.Join(dir, "wslpath"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func TestTranslateArgs(t *testing.T) {
+	fakeWslpath(t)
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "foo.c"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(dir)
+	winPath := func(wslPath string) string { return "W:" + strings.ReplaceAll(wslPath, "/", `\`) }
+	winFoo := winPath(dir + "/foo.c")
+
+	cfg := &config.Config{Args: config.ArgsConfig{
+		Translate: true,
+		Programs: map[string]config.ProgramArgs{
+			"cc":  {Paths: []int{2}},
+			"git": {Raw: true},
+		},
+	}}
+
+	tests := []struct {
+		name    string
+		target  string
+		args    []string
+		rawArgs bool
+		want    []string
+	}{
+		{"existing path", "code", []string{"./foo.c", "--new-window"}, false, []string{winFoo, "--new-window"}},
+		{"flag value", "code.exe", []string{"--goto=./foo.c"}, false, []string{"--goto=" + winFoo}},
+		{"bare name untouched", "p4", []string{"edit", "foo.c"}, false, []string{"edit", "foo.c"}},
+		{"raw-args", "code", []string{"./foo.c"}, true, []string{"./foo.c"}},
+		{"raw program", `C:\Git\git.EXE`, []string{"./foo.c"}, false, []string{"./foo.c"}},
+		{"forced positions", "cc", []string{"./foo.c", "./out.o"}, false, []string{"./foo.c", winPath(dir + "/out.o")}},
+	}
+	for _, tt := range tests {
+		p := &pipeline{
+			ctx:  context.Background(),
+			opts: &Options{RawArgs: tt.rawArgs},
+			cfg:  cfg,
+			conv: pathconv.NewConverter(nil, nil, false),
+		}
+		got, err := p.translateArgs(tt.target, tt.args)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: translateArgs(%q, %q) = %q, want %q", tt.name, tt.target, tt.args, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package launch
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// RunBatch launches every target read from r, one per line, sharing a single
+// wstart-host --serve session when the helper supports it. Each target gets
+// opts.Args. A failing target is reported on stderr and does not stop the
+// batch; the returned exit code is that of the first failed target.
+func RunBatch(opts *Options, r io.Reader) (*Result, error) {
+	sw := newStopwatch(opts.Timing)
+	defer sw.total()
+
+	var open func(context.Context, string) (*Session, error)
+	if !opts.DryRun {
+		open = OpenSession
+	}
+	p, err := newPipeline(context.Background(), opts, sw, open)
+	if err != nil {
+		return nil, err
+	}
+
+	exitCode := 0
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		target := strings.TrimSpace(sc.Text())
+		if target == "" {
+			continue
+		}
+		req, err := p.request(target, opts.Args)
+		if err == nil {
+			_, err = p.launch(req)
+		}
+		reportTarget(opts, target, err)
+		if err != nil && exitCode == 0 {
+			exitCode = ExitCode(err)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		p.close()
+		return nil, fmt.Errorf("reading targets: %w", err)
+	}
+	if err := p.close(); err != nil {
+		return nil, err
+	}
+
+	return &Result{ExitCode: exitCode}, nil
+}
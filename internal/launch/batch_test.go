@@ -0,0 +1,85 @@
+package launch
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sverrirab/wsl-host-start/internal/protocol"
+)
+
+// setupServeHelper points findHelper at the test binary acting as a
+// --serve-capable helper. It returns the path of the log the fake helper
+// appends launched files to.
+func setupServeHelper(t *testing.T) string {
+	t.Helper()
+	return setupFakeHelper(t, "serve")
+}
+
+// setupFakeHelper points findHelper at the test binary running the given
+// fakeHelper mode, with an isolated cache directory and launch log.
+func setupFakeHelper(t *testing.T, mode string) string {
+	t.Helper()
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("WSTART_TEST_HELPER", mode)
+	t.Setenv("WSTART_HOST_PATH", testBinary(t))
+	log := filepath.Join(t.TempDir(), "launched.log")
+	t.Setenv("WSTART_TEST_LOG", log)
+	return log
+}
+
+func TestRunBatchSingleSession(t *testing.T) {
+	log := setupServeHelper(t)
+
+	targets := "notepad.exe\n\n  explorer.exe  \nmspaint.exe\n"
+	res, err := RunBatch(&Options{}, strings.NewReader(targets))
+	if err != nil {
+		t.Fatalf("RunBatch: %v", err)
+	}
+	if res.ExitCode != 0 {
+		t.Errorf("ExitCode = %d, want 0", res.ExitCode)
+	}
+
+	data, err := os.ReadFile(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "notepad.exe\nexplorer.exe\nmspaint.exe\n"
+	if string(data) != want {
+		t.Errorf("launched = %q, want %q", data, want)
+	}
+}
+
+func TestRunBatchPerTargetFailure(t *testing.T) {
+	log := setupServeHelper(t)
+
+	res, err := RunBatch(&Options{}, strings.NewReader("missing.exe\nnotepad.exe\n"))
+	if err != nil {
+		t.Fatalf("RunBatch: %v", err)
+	}
+	if res.ExitCode != protocol.ExitNotFound {
+		t.Errorf("ExitCode = %d, want %d when a target is not found", res.ExitCode, protocol.ExitNotFound)
+	}
+
+	// The failure does not stop the rest of the batch.
+	data, _ := os.ReadFile(log)
+	if string(data) != "missing.exe\nnotepad.exe\n" {
+		t.Errorf("launched = %q", data)
+	}
+}
+
+func TestRunBatchRejectsStdinTarget(t *testing.T) {
+	log := setupServeHelper(t)
+
+	res, err := RunBatch(&Options{}, strings.NewReader("-\nnotepad.exe\n"))
+	if err != nil {
+		t.Fatalf("RunBatch: %v", err)
+	}
+	if res.ExitCode != protocol.ExitInternal {
+		t.Errorf("ExitCode = %d, want %d for the - target", res.ExitCode, protocol.ExitInternal)
+	}
+	if data, _ := os.ReadFile(log); string(data) != "notepad.exe\n" {
+		t.Errorf("launched = %q", data)
+	}
+}
@@ -1,14 +1,18 @@
 package launch
 
 import (
+	"context"
 	"fmt"
 	"io"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 
 	"github.com/sverrirab/wsl-host-start/internal/allowlist"
 	"github.com/sverrirab/wsl-host-start/internal/config"
+	"github.com/sverrirab/wsl-host-start/internal/envpolicy"
 )
 
 // configReport holds the diagnostic information about the active configuration.
@@ -21,14 +25,31 @@ type configReport struct {
 	Config       *config.Config
 
 	// Allowlist
-	AllowlistLoaded bool
-	AllowlistPath   string
-	AllowlistRules  []allowlist.Rule
+	AllowlistLoaded  bool
+	AllowlistPath    string
+	AllowlistRules   []allowlist.Rule
+	AllowlistSchemes []string
+	AllowlistPolicy  allowlist.Policy
 
 	// Env analysis
-	ForwardedVars []string // vars that would be forwarded (set in env and not blocked)
-	BlockedVars   []string // vars in forward list that are blocked
-	MissingVars   []string // vars in forward list that are not set in env
+	ForwardedVars []string          // vars that would be forwarded (set in env and not blocked)
+	BlockedVars   []string          // vars in forward list that are blocked
+	MissingVars   []string          // vars in forward list that are not set in env
+	EnvPatterns   map[string]string // forward or block entry that decided each var
+
+	// Effective env forwarding per [env.programs] section, by program name.
+	EnvPrograms []programEnv
+}
+
+// programEnv is the env analysis for one program with its own section.
+type programEnv struct {
+	Program       string
+	Forward       []string // the section's forward list
+	Block         []string // global block list plus the section's
+	ForwardedVars []string
+	BlockedVars   []string
+	MissingVars   []string
+	EnvPatterns   map[string]string
 }
 
 // CheckConfig loads the active configuration and prints a diagnostic report.
@@ -45,7 +66,7 @@ func CheckConfig(verbose bool) error {
 func buildConfigReport() (*configReport, error) {
 	report := &configReport{}
 
-	helperPath, err := findHelper()
+	helperPath, err := findHelper(context.Background())
 	if err != nil {
 		return nil, err
 	}
@@ -67,29 +88,51 @@ func buildConfigReport() (*configReport, error) {
 	report.AllowlistPath = al.Path
 	if al.Loaded && al.List != nil {
 		report.AllowlistRules = al.List.Allow
+		report.AllowlistSchemes = al.List.Schemes
+		report.AllowlistPolicy = al.List.Policy
 	}
 
 	// Analyze env forwarding.
-	blocked := make(map[string]bool)
-	for _, b := range cfg.Env.Block {
-		blocked[strings.ToUpper(b)] = true
-	}
-	for _, name := range cfg.Env.Forward {
-		upper := strings.ToUpper(name)
-		if blocked[upper] {
-			report.BlockedVars = append(report.BlockedVars, name)
-			continue
-		}
-		if _, ok := os.LookupEnv(name); ok {
-			report.ForwardedVars = append(report.ForwardedVars, name)
-		} else {
-			report.MissingVars = append(report.MissingVars, name)
-		}
+	report.ForwardedVars, report.BlockedVars, report.MissingVars, report.EnvPatterns = analyzeEnv(cfg.Env)
+	for _, name := range slices.Sorted(maps.Keys(cfg.Env.Programs)) {
+		prog := strings.TrimSuffix(strings.ToLower(name), ".exe")
+		env := cfg.Env.ForProgram(prog)
+		pe := programEnv{Program: prog, Forward: env.Forward, Block: env.Block}
+		pe.ForwardedVars, pe.BlockedVars, pe.MissingVars, pe.EnvPatterns = analyzeEnv(env)
+		report.EnvPrograms = append(report.EnvPrograms, pe)
 	}
 
 	return report, nil
 }
 
+// analyzeEnv sorts the variables that env's forward list selects into
+// forwarded and blocked ones, lists the forward entries that match no
+// variable that is set, and records the entry that decided each variable.
+func analyzeEnv(env config.EnvConfig) (forwarded, blocked, missing []string, patterns map[string]string) {
+	patterns = make(map[string]string)
+	matches, unset := matchEnv(env)
+	for _, m := range matches {
+		switch {
+		case m.blocked != "":
+			blocked = append(blocked, m.name)
+			patterns[m.name] = m.blocked
+		case parseEnvSpec(m.entry).toWSL:
+		default:
+			forwarded = append(forwarded, m.name)
+			patterns[m.name] = envpolicy.Name(m.entry)
+		}
+	}
+	for _, name := range unset {
+		if pattern, ok := envpolicy.Match(env.Block, name); ok && !envpolicy.IsPattern(name) {
+			blocked = append(blocked, name)
+			patterns[name] = pattern
+			continue
+		}
+		missing = append(missing, name)
+	}
+	return forwarded, blocked, missing, patterns
+}
+
 // checkConfigReport generates the diagnostic text for a given report.
 // Extracted for testability.
 func checkConfigReport(w io.Writer, report *configReport, verbose bool) {
@@ -119,13 +162,14 @@ func printConfigReport(w io.Writer, report *configReport, verbose bool) {
 	} else {
 		fmt.Fprintf(w, "Status:    ACTIVE (%d rules)\n", len(report.AllowlistRules))
 		for _, rule := range report.AllowlistRules {
-			if len(rule.Commands) == 0 {
-				fmt.Fprintf(w, "  allow:   %s (any args)\n", rule.Program)
-			} else {
-				fmt.Fprintf(w, "  allow:   %s [%s]\n", rule.Program, strings.Join(rule.Commands, ", "))
-			}
+			fmt.Fprintf(w, "  allow:   %s\n", rule)
 		}
 	}
+	if report.AllowlistLoaded {
+		fmt.Fprintf(w, "Schemes:   %s\n", schemesSummary(report.AllowlistSchemes))
+		fmt.Fprintf(w, "Verbs:     %s (rules without their own verbs)\n", strings.Join(report.AllowlistPolicy.DefaultVerbs(), ", "))
+		fmt.Fprintf(w, "Elevation: %s\n", report.AllowlistPolicy.ElevationSummary())
+	}
 
 	// Env forwarding
 	fmt.Fprintf(w, "\n--- Environment ---\n")
@@ -134,20 +178,26 @@ func printConfigReport(w io.Writer, report *configReport, verbose bool) {
 	} else {
 		fmt.Fprintf(w, "Forward:   %s\n", strings.Join(report.Config.Env.Forward, ", "))
 	}
+	if report.Config.Env.MergeWSLENV {
+		fmt.Fprintf(w, "WSLENV:    merged into the forward list\n")
+	}
 	if len(report.Config.Env.Block) == 0 {
 		fmt.Fprintf(w, "Block:     (none)\n")
 	} else {
 		fmt.Fprintf(w, "Block:     %s\n", strings.Join(report.Config.Env.Block, ", "))
 	}
+	fmt.Fprintf(w, "Protected: %s (never forwarded)\n", strings.Join(envpolicy.ProtectedNames(), ", "))
 
-	if len(report.ForwardedVars) > 0 {
-		fmt.Fprintf(w, "Will forward:  %s\n", strings.Join(report.ForwardedVars, ", "))
-	}
-	if len(report.BlockedVars) > 0 {
-		fmt.Fprintf(w, "Blocked:       %s (in forward list but blocked)\n", strings.Join(report.BlockedVars, ", "))
+	printEnvVars(w, "", report.ForwardedVars, report.BlockedVars, report.MissingVars, report.EnvPatterns)
+
+	for _, pe := range report.EnvPrograms {
+		fmt.Fprintf(w, "\nProgram:   %s\n", pe.Program)
+		fmt.Fprintf(w, "  Forward: %s\n", listOrNone(pe.Forward))
+		fmt.Fprintf(w, "  Block:   %s\n", listOrNone(pe.Block))
+		printEnvVars(w, "  ", pe.ForwardedVars, pe.BlockedVars, pe.MissingVars, pe.EnvPatterns)
 	}
-	if len(report.MissingVars) > 0 {
-		fmt.Fprintf(w, "Not set:       %s (in forward list but not in environment)\n", strings.Join(report.MissingVars, ", "))
+	if len(report.EnvPrograms) > 0 {
+		fmt.Fprintf(w, "\nOther programs use the global lists.\n")
 	}
 
 	// Drive config
@@ -166,3 +216,45 @@ func printConfigReport(w io.Writer, report *configReport, verbose bool) {
 		fmt.Fprintf(w, "Show: %s\n", report.Config.Defaults.Show)
 	}
 }
+
+// printEnvVars prints the outcome of analyzeEnv, each line prefixed with indent.
+func printEnvVars(w io.Writer, indent string, forwarded, blocked, missing []string, patterns map[string]string) {
+	if len(forwarded) > 0 {
+		fmt.Fprintf(w, "%sWill forward:  %s\n", indent, withPatterns(forwarded, patterns))
+	}
+	if len(blocked) > 0 {
+		fmt.Fprintf(w, "%sBlocked:       %s (in forward list but blocked)\n", indent, withPatterns(blocked, patterns))
+	}
+	if len(missing) > 0 {
+		fmt.Fprintf(w, "%sNot set:       %s (in forward list but not in environment)\n", indent, strings.Join(missing, ", "))
+	}
+}
+
+// listOrNone joins entries, or returns "(none)" if there are none.
+func listOrNone(entries []string) string {
+	if len(entries) == 0 {
+		return "(none)"
+	}
+	return strings.Join(entries, ", ")
+}
+
+// withPatterns joins names, annotating each with the list entry that
+// decided it when that is a pattern, e.g. "P4PORT (P4*)".
+func withPatterns(names []string, patterns map[string]string) string {
+	out := make([]string, len(names))
+	for i, name := range names {
+		out[i] = name
+		if p := patterns[name]; p != "" && !strings.EqualFold(p, name) {
+			out[i] = fmt.Sprintf("%s (%s)", name, p)
+		}
+	}
+	return strings.Join(out, ", ")
+}
+
+// schemesSummary lists the URI schemes an active allowlist permits.
+func schemesSummary(schemes []string) string {
+	if len(schemes) == 0 {
+		return "(none — all URIs DENIED)"
+	}
+	return strings.Join(schemes, ", ")
+}
@@ -56,6 +56,7 @@ func TestCheckConfigReportEmptyAllowlist(t *testing.T) {
 
 	assertContains(t, out, "ACTIVE (empty")
 	assertContains(t, out, "all programs DENIED")
+	assertContains(t, out, "all URIs DENIED")
 }
 
 func TestCheckConfigReportWithRules(t *testing.T) {
@@ -69,6 +70,8 @@ func TestCheckConfigReportWithRules(t *testing.T) {
 			{Program: "p4", Commands: []string{"edit", "sync"}},
 			{Program: "notepad.exe"},
 		},
+		AllowlistSchemes: []string{"https", "mailto"},
+		AllowlistPolicy:  allowlist.Policy{Elevation: allowlist.ElevationDeny},
 		Config: &config.Config{
 			Env:      config.EnvConfig{},
 			Drives:   config.DrivesConfig{AutoDetect: true},
@@ -83,6 +86,9 @@ func TestCheckConfigReportWithRules(t *testing.T) {
 	assertContains(t, out, "ACTIVE (2 rules)")
 	assertContains(t, out, "p4 [edit, sync]")
 	assertContains(t, out, "notepad.exe (any args)")
+	assertContains(t, out, "Schemes:   https, mailto")
+	assertContains(t, out, "Verbs:     open (rules without their own verbs)")
+	assertContains(t, out, "Elevation: deny")
 }
 
 func TestCheckConfigReportEnvAnalysis(t *testing.T) {
@@ -120,6 +126,58 @@ func TestCheckConfigReportEnvAnalysis(t *testing.T) {
 	assertContains(t, out, "Not set:       P4USER")
 }
 
+func TestCheckConfigReportEnvPatterns(t *testing.T) {
+	report := &launch.ConfigReport{
+		HelperPath:    "/mnt/c/wstart/wstart-host.exe",
+		HelperDir:     "/mnt/c/wstart",
+		ConfigLoaded:  true,
+		AllowlistPath: "/mnt/c/wstart/allowlist.toml",
+		Config: &config.Config{
+			Env: config.EnvConfig{
+				Forward: []string{"P4*", "EDITOR"},
+				Block:   []string{"*PASSWD*"},
+			},
+		},
+		ForwardedVars: []string{"EDITOR", "P4PORT"},
+		BlockedVars:   []string{"P4PASSWD"},
+		EnvPatterns:   map[string]string{"EDITOR": "EDITOR", "P4PORT": "P4*", "P4PASSWD": "*PASSWD*"},
+	}
+
+	var buf bytes.Buffer
+	launch.CheckConfigReport(&buf, report, false)
+	out := buf.String()
+
+	assertContains(t, out, "Will forward:  EDITOR, P4PORT (P4*)")
+	assertContains(t, out, "Blocked:       P4PASSWD (*PASSWD*)")
+}
+
+func TestCheckConfigReportEnvPrograms(t *testing.T) {
+	report := &launch.ConfigReport{
+		HelperPath:    "/mnt/c/wstart/wstart-host.exe",
+		HelperDir:     "/mnt/c/wstart",
+		ConfigLoaded:  true,
+		AllowlistPath: "/mnt/c/wstart/allowlist.toml",
+		Config: &config.Config{
+			Env: config.EnvConfig{Forward: []string{"EDITOR"}},
+		},
+		ForwardedVars: []string{"EDITOR"},
+		EnvPrograms: []launch.ProgramEnv{{
+			Program:       "p4",
+			Forward:       []string{"P4*"},
+			Block:         []string{"P4PASSWD"},
+			ForwardedVars: []string{"P4PORT"},
+			EnvPatterns:   map[string]string{"P4PORT": "P4*"},
+		}},
+	}
+
+	var buf bytes.Buffer
+	launch.CheckConfigReport(&buf, report, false)
+	out := buf.String()
+
+	assertContains(t, out, "Program:   p4\n  Forward: P4*\n  Block:   P4PASSWD\n  Will forward:  P4PORT (P4*)")
+	assertContains(t, out, "Other programs use the global lists.")
+}
+
 func TestCheckConfigReportVerbose(t *testing.T) {
 	report := &launch.ConfigReport{
 		HelperPath:      "/mnt/c/wstart/wstart-host.exe",
@@ -0,0 +1,90 @@
+package launch
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/sverrirab/wsl-host-start/internal/protocol"
+)
+
+// RunEach launches every target with no program arguments (wstart -each).
+// Each target is translated individually; those that translate are sent
+// to the helper together in one --request exchange when it advertises the
+// "multi" capability, otherwise one at a time. Per-target outcomes are
+// reported on stderr and returned in Result.Targets; the exit code is that
+// of the first failed target.
+func RunEach(opts *Options, targets []string) (*Result, error) {
+	if slices.Contains(targets, StdinTarget) {
+		return nil, errStdinTarget
+	}
+	sw := newStopwatch(opts.Timing)
+	defer sw.total()
+
+	p, err := newPipeline(context.Background(), opts, sw, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]TargetResult, len(targets))
+	var reqs []protocol.LaunchRequest
+	var pending []int // index into results for each entry in reqs
+	for i, target := range targets {
+		results[i].Target = target
+		req, err := p.request(target, nil)
+		if err != nil {
+			results[i].Err = err
+			continue
+		}
+		reqs = append(reqs, *req)
+		pending = append(pending, i)
+	}
+
+	switch {
+	case len(reqs) == 0:
+	case opts.DryRun || !p.hello.Has(protocol.CapMulti):
+		for j := range reqs {
+			res, err := p.launch(&reqs[j])
+			if err != nil {
+				results[pending[j]].Err = err
+			} else {
+				results[pending[j]].PID = res.PID
+			}
+		}
+	default:
+		resps, err := p.launchMulti(reqs)
+		for j, i := range pending {
+			if err != nil {
+				results[i].Err = err
+				continue
+			}
+			p.warnDroppedEnv(&resps[j])
+			res, lerr := launchResult(&resps[j])
+			if lerr != nil {
+				results[i].Err = lerr
+			} else {
+				results[i].PID = res.PID
+			}
+		}
+	}
+
+	result := &Result{Targets: results}
+	for _, r := range results {
+		reportTarget(opts, r.Target, r.Err)
+		if r.Err != nil && result.ExitCode == 0 {
+			result.ExitCode = ExitCode(r.Err)
+		}
+	}
+	return result, nil
+}
+
+// reportTarget prints the outcome for one target of a multi-target or
+// batch launch: failures always, successes with -verbose.
+func reportTarget(opts *Options, target string, err error) {
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "wstart: %s: %v\n", target, err)
+	} else if opts.Verbose {
+		fmt.Fprintf(os.Stderr, "wstart: %s: ok\n", target)
+	}
+}
@@ -0,0 +1,62 @@
+package launch
+
+import (
+	"os"
+	"testing"
+
+	"github.com/sverrirab/wsl-host-start/internal/protocol"
+)
+
+func TestRunEachOneRequest(t *testing.T) {
+	log := setupFakeHelper(t, "multi")
+
+	targets := []string{"notepad.exe", "missing.exe", "./does/not/translate.txt", "mspaint.exe"}
+	res, err := RunEach(&Options{}, targets)
+	if err != nil {
+		t.Fatalf("RunEach: %v", err)
+	}
+	if res.ExitCode != protocol.ExitNotFound {
+		t.Errorf("ExitCode = %d, want %d for the first failed target", res.ExitCode, protocol.ExitNotFound)
+	}
+	if len(res.Targets) != len(targets) {
+		t.Fatalf("got %d target results, want %d", len(res.Targets), len(targets))
+	}
+
+	for i, wantErr := range []bool{false, true, true, false} {
+		r := res.Targets[i]
+		if r.Target != targets[i] {
+			t.Errorf("Targets[%d].Target = %q, want %q", i, r.Target, targets[i])
+		}
+		if (r.Err != nil) != wantErr {
+			t.Errorf("Targets[%d] (%s): err = %v, wantErr %v", i, r.Target, r.Err, wantErr)
+		}
+	}
+	if res.Targets[3].PID != 3 {
+		t.Errorf("mspaint PID = %d, want 3 (third entry in the batched request)", res.Targets[3].PID)
+	}
+
+	// One --request carried every translatable target; the untranslatable
+	// one never reached the helper.
+	data, _ := os.ReadFile(log)
+	want := "request\nnotepad.exe\nmissing.exe\nmspaint.exe\n"
+	if string(data) != want {
+		t.Errorf("helper log = %q, want %q", data, want)
+	}
+}
+
+func TestRunEachAllSucceed(t *testing.T) {
+	setupFakeHelper(t, "multi")
+
+	res, err := RunEach(&Options{}, []string{"notepad.exe", "explorer.exe"})
+	if err != nil {
+		t.Fatalf("RunEach: %v", err)
+	}
+	if res.ExitCode != 0 {
+		t.Errorf("ExitCode = %d, want 0", res.ExitCode)
+	}
+	for _, r := range res.Targets {
+		if r.Err != nil {
+			t.Errorf("%s: %v", r.Target, r.Err)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package launch
+
+import (
+	"context"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/sverrirab/wsl-host-start/internal/config"
+	"github.com/sverrirab/wsl-host-start/internal/envpolicy"
+	"github.com/sverrirab/wsl-host-start/internal/pathconv"
+)
+
+// envSpec is one entry of the forward list: a variable name with
+// WSLENV-style flags, e.g. "P4CONFIG/p" or "PYTHONPATH/l".
+type envSpec struct {
+	name  string
+	path  bool // /p: the value is a path
+	list  bool // /l: the value is a colon-separated list of paths
+	toWSL bool // /w: only forwarded from Windows to WSL, so never by wstart
+}
+
+// parseEnvSpec splits a forward list entry into its name and flags. The
+// /u flag (WSL to Windows only) is accepted and is the default here.
+func parseEnvSpec(entry string) envSpec {
+	name, flags, _ := strings.Cut(entry, "/")
+	spec := envSpec{name: name}
+	for _, f := range flags {
+		switch f {
+		case 'p':
+			spec.path = true
+		case 'l':
+			spec.list = true
+		case 'w':
+			spec.toWSL = true
+		}
+	}
+	if spec.toWSL && strings.ContainsRune(flags, 'u') {
+		spec.toWSL = false
+	}
+	return spec
+}
+
+// forwardEntries returns the forward list of env. With merge_wslenv,
+// entries of $WSLENV are added after the configured ones; a variable
+// listed in both keeps the configured flags.
+func forwardEntries(env config.EnvConfig) []string {
+	var entries []string
+	seen := make(map[string]bool)
+	add := func(entry string) {
+		name := strings.ToUpper(envpolicy.Name(entry))
+		if name == "" || seen[name] {
+			return
+		}
+		seen[name] = true
+		entries = append(entries, entry)
+	}
+	for _, entry := range env.Forward {
+		add(entry)
+	}
+	if env.MergeWSLENV {
+		for _, entry := range strings.Split(os.Getenv("WSLENV"), ":") {
+			add(entry)
+		}
+	}
+	return entries
+}
+
+// envMatch is a set variable that the forward list selects.
+type envMatch struct {
+	name    string
+	entry   string // forward entry that matched, e.g. "P4*" or "P4CONFIG/p"
+	blocked string // block entry that matched; the variable is not forwarded
+}
+
+// matchEnv applies the forward and block lists of env to the environment.
+// It returns the selected variables sorted by name, and the forward
+// entries that select no variable that is set.
+func matchEnv(env config.EnvConfig) (matches []envMatch, missing []string) {
+	entries := forwardEntries(env)
+	if len(entries) == 0 {
+		return nil, nil
+	}
+	policy := envpolicy.Policy{Forward: entries, Block: env.Block}
+
+	used := make(map[string]bool)
+	for _, kv := range os.Environ() {
+		name, _, _ := strings.Cut(kv, "=")
+		entry, ok := envpolicy.Match(entries, name)
+		if !ok {
+			continue
+		}
+		used[entry] = true
+		m := envMatch{name: name, entry: entry}
+		if d := policy.Decide(name); d.Blocked {
+			m.blocked = d.Pattern
+		}
+		matches = append(matches, m)
+	}
+	slices.SortFunc(matches, func(a, b envMatch) int { return strings.Compare(a.name, b.name) })
+
+	for _, entry := range entries {
+		if !used[entry] {
+			missing = append(missing, envpolicy.Name(entry))
+		}
+	}
+	return matches, missing
+}
+
+// collectEnvVars returns the variables to forward to the named program
+// (see allowlist.ProgramName; "" for the global lists): those its forward
+// list (and $WSLENV if merged) selects that are set and not blocked.
+// Values of /p and /l entries are translated to Windows paths with conv; a
+// nil conv, or a value that cannot be translated, is forwarded unchanged.
+func collectEnvVars(ctx context.Context, cfg *config.Config, program string, conv *pathconv.Converter) map[string]string {
+	matches, _ := matchEnv(cfg.Env.ForProgram(program))
+
+	vars := make(map[string]string)
+	for _, m := range matches {
+		spec := parseEnvSpec(m.entry)
+		if m.blocked != "" || spec.toWSL {
+			continue
+		}
+		val := os.Getenv(m.name)
+		if conv != nil && val != "" {
+			switch {
+			case spec.list:
+				val = translatePathList(ctx, conv, val)
+			case spec.path:
+				if win, err := conv.ToWindowsContext(ctx, val); err == nil {
+					val = win
+				}
+			}
+		}
+		vars[m.name] = val
+	}
+
+	if len(vars) == 0 {
+		return nil
+	}
+	return vars
+}
+
+// translatePathList converts a colon-separated list of WSL paths to a
+// semicolon-separated list of Windows paths. Empty elements are dropped;
+// elements that cannot be translated are kept as they are.
+func translatePathList(ctx context.Context, conv *pathconv.Converter, list string) string {
+	var out []string
+	for _, p := range strings.Split(list, ":") {
+		if p == "" {
+			continue
+		}
+		if win, err := conv.ToWindowsContext(ctx, p); err == nil {
+			p = win
+		}
+		out = append(out, p)
+	}
+	return strings.Join(out, ";")
+}
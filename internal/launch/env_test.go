@@ -1,6 +1,7 @@
 package launch_test
 
 import (
+	"context"
 	"testing"
 
 	"github.com/sverrirab/wsl-host-start/internal/config"
@@ -50,6 +51,13 @@ func TestCollectEnvVars(t *testing.T) {
 			setEnv:  map[string]string{"SECRET": "value"},
 			want:    nil,
 		},
+		{
+			name:    "protected vars are never forwarded",
+			forward: []string{"PATHEXT", "COMSPEC", "P4PORT"},
+			block:   []string{},
+			setEnv:  map[string]string{"PATHEXT": ".SH", "COMSPEC": "/bin/sh", "P4PORT": "ssl:host:1666"},
+			want:    map[string]string{"P4PORT": "ssl:host:1666"},
+		},
 		{
 			name:    "same var in forward and block - block wins",
 			forward: []string{"P4TICKETS"},
@@ -94,7 +102,7 @@ func TestCollectEnvVars(t *testing.T) {
 				},
 			}
 
-			got := launch.CollectEnvVars(cfg)
+			got := launch.CollectEnvVars(context.Background(), cfg, "", nil)
 
 			if tt.want == nil {
 				if got != nil {
@@ -0,0 +1,93 @@
+package launch
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/sverrirab/wsl-host-start/internal/config"
+	"github.com/sverrirab/wsl-host-start/internal/envpolicy"
+	"github.com/sverrirab/wsl-host-start/internal/protocol"
+)
+
+// ParseEnvAssignment splits a NAME=VALUE argument of wstart -env.
+func ParseEnvAssignment(s string) (name, value string, err error) {
+	name, value, ok := strings.Cut(s, "=")
+	if !ok || name == "" {
+		return "", "", fmt.Errorf("invalid environment assignment %q (want NAME=VALUE)", s)
+	}
+	return name, value, nil
+}
+
+// ReadEnvFile reads the variables of wstart -env-from: one NAME=VALUE per
+// line, as in a .env file. Blank lines and lines starting with # are
+// skipped, an "export " prefix is ignored, and a value enclosed in
+// matching single or double quotes is unquoted.
+func ReadEnvFile(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	vars, err := parseEnvFile(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return vars, nil
+}
+
+func parseEnvFile(r io.Reader) (map[string]string, error) {
+	vars := make(map[string]string)
+	sc := bufio.NewScanner(r)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		name, value, err := ParseEnvAssignment(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		name = strings.TrimSpace(name)
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		vars[name] = value
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return vars, nil
+}
+
+// adHocEnv checks the variables given with -env and -env-from against
+// the block list that applies to the program, and returns their names in
+// order. Unlike forwarded variables, a blocked one is an error: the user
+// asked for it explicitly.
+func adHocEnv(env config.EnvConfig, vars map[string]string) ([]string, error) {
+	if len(vars) == 0 {
+		return nil, nil
+	}
+	if !env.AllowAdHoc {
+		return nil, withExit(protocol.ExitDenied, withKind(ErrPolicyDenied,
+			fmt.Errorf("-env is disabled by allow_adhoc = false in %s", config.ConfigFile)))
+	}
+	names := slices.Sorted(maps.Keys(vars))
+	policy := envpolicy.Policy{Block: env.Block, AdHoc: names}
+	for _, name := range names {
+		d := policy.Decide(name)
+		if d.Forward {
+			continue
+		}
+		reason := envpolicy.Removal{Name: name, Pattern: d.Pattern}
+		return nil, withExit(protocol.ExitDenied, withKind(ErrPolicyDenied,
+			fmt.Errorf("-env %s cannot be forwarded", reason)))
+	}
+	return names, nil
+}
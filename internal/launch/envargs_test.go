@@ -0,0 +1,82 @@
+package launch
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sverrirab/wsl-host-start/internal/config"
+	"github.com/sverrirab/wsl-host-start/internal/protocol"
+)
+
+func TestParseEnvAssignment(t *testing.T) {
+	name, value, err := ParseEnvAssignment("P4CLIENT=ws=2")
+	if err != nil || name != "P4CLIENT" || value != "ws=2" {
+		t.Errorf("ParseEnvAssignment = %q, %q, %v; want P4CLIENT, ws=2", name, value, err)
+	}
+	if _, value, err := ParseEnvAssignment("EMPTY="); err != nil || value != "" {
+		t.Errorf("ParseEnvAssignment(EMPTY=) = %q, %v", value, err)
+	}
+	for _, bad := range []string{"NOVALUE", "=value", ""} {
+		if _, _, err := ParseEnvAssignment(bad); err == nil {
+			t.Errorf("ParseEnvAssignment(%q): want error", bad)
+		}
+	}
+}
+
+func TestParseEnvFile(t *testing.T) {
+	got, err := parseEnvFile(strings.NewReader(`
+# Perforce
+P4CLIENT=ws2
+export P4PORT = "ssl:perforce:1666"
+GREETING='hello world'
+EMPTY=
+`))
+	if err != nil {
+		t.Fatalf("parseEnvFile: %v", err)
+	}
+	want := map[string]string{
+		"P4CLIENT": "ws2",
+		"P4PORT":   "ssl:perforce:1666",
+		"GREETING": "hello world",
+		"EMPTY":    "",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseEnvFile = %v, want %v", got, want)
+	}
+
+	if _, err := parseEnvFile(strings.NewReader("A=1\nbroken\n")); err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("parseEnvFile(broken) error = %v, want line 2", err)
+	}
+}
+
+func TestAdHocEnv(t *testing.T) {
+	env := config.EnvConfig{Block: []string{"*PASSWD*"}, AllowAdHoc: true}
+
+	names, err := adHocEnv(env, map[string]string{"P4CLIENT": "ws2", "DEBUG": "1"})
+	if err != nil || !reflect.DeepEqual(names, []string{"DEBUG", "P4CLIENT"}) {
+		t.Errorf("adHocEnv = %q, %v; want DEBUG, P4CLIENT", names, err)
+	}
+
+	for name, want := range map[string]string{
+		"P4PASSWD": "P4PASSWD (blocked by *PASSWD*)",
+		"PATH":     "PATH (protected)",
+	} {
+		_, err := adHocEnv(env, map[string]string{name: "x"})
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("adHocEnv(%s) error = %v, want %q", name, err, want)
+		}
+		if !errors.Is(err, ErrPolicyDenied) || ExitCode(err) != protocol.ExitDenied {
+			t.Errorf("adHocEnv(%s) error = %v, want a policy denial", name, err)
+		}
+	}
+
+	env.AllowAdHoc = false
+	if _, err := adHocEnv(env, map[string]string{"DEBUG": "1"}); !errors.Is(err, ErrPolicyDenied) {
+		t.Errorf("adHocEnv with allow_adhoc = false: error = %v, want a policy denial", err)
+	}
+	if names, err := adHocEnv(env, nil); names != nil || err != nil {
+		t.Errorf("adHocEnv(nil) = %q, %v", names, err)
+	}
+}
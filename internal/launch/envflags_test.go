@@ -0,0 +1,148 @@
+package launch
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/sverrirab/wsl-host-start/internal/config"
+	"github.com/sverrirab/wsl-host-start/internal/pathconv"
+)
+
+func TestParseEnvSpec(t *testing.T) {
+	tests := []struct {
+		entry string
+		want  envSpec
+	}{
+		{"P4PORT", envSpec{name: "P4PORT"}},
+		{"P4CONFIG/p", envSpec{name: "P4CONFIG", path: true}},
+		{"PYTHONPATH/l", envSpec{name: "PYTHONPATH", list: true}},
+		{"GOPATH/up", envSpec{name: "GOPATH", path: true}},
+		{"USERPROFILE/pw", envSpec{name: "USERPROFILE", path: true, toWSL: true}},
+		{"BOTH/wu", envSpec{name: "BOTH"}},
+	}
+	for _, tt := range tests {
+		if got := parseEnvSpec(tt.entry); got != tt.want {
+			t.Errorf("parseEnvSpec(%q) = %+v, want %+v", tt.entry, got, tt.want)
+		}
+	}
+}
+
+func TestCollectEnvVarsTranslatesPaths(t *testing.T) {
+	fakeWslpath(t)
+	t.Setenv("P4CONFIG", "/home/me/ws/.p4config")
+	t.Setenv("PYTHONPATH", "/home/me/lib::/opt/py")
+	t.Setenv("P4PORT", "ssl:host:1666")
+	t.Setenv("USERPROFILE", "/mnt/c/Users/me")
+
+	cfg := &config.Config{Env: config.EnvConfig{
+		Forward: []string{"P4CONFIG/p", "PYTHONPATH/l", "P4PORT", "USERPROFILE/pw"},
+	}}
+	conv := pathconv.NewConverter(nil, nil, false)
+
+	got := collectEnvVars(context.Background(), cfg, "", conv)
+	want := map[string]string{
+		"P4CONFIG":   `W:\home\me\ws\.p4config`,
+		"PYTHONPATH": `W:\home\me\lib;W:\opt\py`,
+		"P4PORT":     "ssl:host:1666",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("collectEnvVars = %v, want %v", got, want)
+	}
+}
+
+func TestForwardEntriesMergeWSLENV(t *testing.T) {
+	t.Setenv("WSLENV", "GOPATH/l:P4CONFIG:WT_SESSION::")
+
+	cfg := &config.Config{Env: config.EnvConfig{Forward: []string{"P4CONFIG/p"}}}
+	if got := forwardEntries(cfg.Env); len(got) != 1 {
+		t.Errorf("without merge_wslenv: got %q, want only the configured entry", got)
+	}
+
+	cfg.Env.MergeWSLENV = true
+	got := forwardEntries(cfg.Env)
+	want := []string{"P4CONFIG/p", "GOPATH/l", "WT_SESSION"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("forwardEntries = %q, want %q", got, want)
+	}
+}
+
+func TestCollectEnvVarsPatterns(t *testing.T) {
+	fakeWslpath(t)
+	t.Setenv("P4PORT", "ssl:host:1666")
+	t.Setenv("P4CLIENT", "ws")
+	t.Setenv("P4PASSWD", "secret")
+	t.Setenv("P4CONFIG", "/home/me/.p4config")
+	t.Setenv("GITHUB_TOKEN", "ghp_x")
+
+	cfg := &config.Config{Env: config.EnvConfig{
+		Forward: []string{"P4*", "P4CONFIG/p", "GITHUB_*"},
+		Block:   []string{"*PASSWD*", "*_TOKEN"},
+	}}
+	got := collectEnvVars(context.Background(), cfg, "", pathconv.NewConverter(nil, nil, false))
+	want := map[string]string{
+		"P4PORT":   "ssl:host:1666",
+		"P4CLIENT": "ws",
+		"P4CONFIG": `W:\home\me\.p4config`,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("collectEnvVars = %v, want %v", got, want)
+	}
+}
+
+func TestMatchEnvMissing(t *testing.T) {
+	t.Setenv("P4PORT", "ssl:host:1666")
+	cfg := &config.Config{Env: config.EnvConfig{Forward: []string{"P4PORT", "NOPE_*", "UNSET_VAR/p"}}}
+
+	matches, missing := matchEnv(cfg.Env)
+	if len(matches) != 1 || matches[0].name != "P4PORT" || matches[0].entry != "P4PORT" {
+		t.Errorf("matches = %+v, want only P4PORT", matches)
+	}
+	if want := []string{"NOPE_*", "UNSET_VAR"}; !reflect.DeepEqual(missing, want) {
+		t.Errorf("missing = %q, want %q", missing, want)
+	}
+}
+
+func TestCollectEnvVarsProgramScope(t *testing.T) {
+	t.Setenv("P4PORT", "ssl:host:1666")
+	t.Setenv("P4CLIENT", "ws")
+	t.Setenv("EDITOR", "code")
+
+	cfg := &config.Config{Env: config.EnvConfig{
+		Forward: []string{"EDITOR"},
+		Block:   []string{"P4PASSWD"},
+		Programs: map[string]config.EnvProgram{
+			"P4.exe": {Forward: []string{"P4*"}, Block: []string{"P4CLIENT"}},
+		},
+	}}
+	tests := []struct {
+		program string
+		want    map[string]string
+	}{
+		{"p4", map[string]string{"P4PORT": "ssl:host:1666"}},
+		{"acrobat", map[string]string{"EDITOR": "code"}},
+		{"", map[string]string{"EDITOR": "code"}},
+	}
+	for _, tt := range tests {
+		got := collectEnvVars(context.Background(), cfg, tt.program, nil)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("collectEnvVars(%q) = %v, want %v", tt.program, got, tt.want)
+		}
+	}
+}
+
+func TestForProgramKeepsGlobalBlock(t *testing.T) {
+	env := config.EnvConfig{
+		Forward:     []string{"EDITOR"},
+		Block:       []string{"*PASSWD*"},
+		MergeWSLENV: true,
+		Programs:    map[string]config.EnvProgram{"p4": {Forward: []string{"P4*"}}},
+	}
+	got := env.ForProgram("p4")
+	if !reflect.DeepEqual(got.Block, []string{"*PASSWD*"}) || got.MergeWSLENV {
+		t.Errorf("ForProgram(p4) = %+v, want global block and no $WSLENV merge", got)
+	}
+	if got := env.ForProgram("code"); !reflect.DeepEqual(got.Forward, env.Forward) || !got.MergeWSLENV {
+		t.Errorf("ForProgram(code) = %+v, want the global lists", got)
+	}
+}
@@ -0,0 +1,167 @@
+package launch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+
+	"github.com/sverrirab/wsl-host-start/internal/protocol"
+)
+
+// Sentinel errors for classifying launch failures with errors.Is. The
+// errors returned by this package keep their own messages and wrap one of
+// these where it applies.
+var (
+	// ErrHelperNotFound means wstart-host.exe could not be located.
+	ErrHelperNotFound = errors.New("wstart-host.exe not found")
+
+	// ErrVersionMismatch means the helper speaks too old a protocol or
+	// lacks a capability the request needs.
+	ErrVersionMismatch = errors.New("host helper version mismatch")
+
+	// ErrPolicyDenied means the host's deny list, allowlist, hash pin or
+	// config signature check refused the launch. A *HelperError with one of
+	// those categories matches it.
+	ErrPolicyDenied = errors.New("denied by host policy")
+
+	// ErrInterop means a Windows process could not be started, or the
+	// context ended before it answered.
+	ErrInterop = errors.New("WSL interop failed")
+)
+
+// HelperError is a launch failure reported by the helper. Category is one
+// of the protocol.Err* constants, or empty for helpers that predate it.
+type HelperError struct {
+	Category string
+	Message  string
+	Code     int
+	Rule     string
+	Path     string
+}
+
+func (e *HelperError) Error() string {
+	msg := "host helper: " + e.Message
+	switch {
+	case e.Category != "" && e.Code != 0:
+		msg += fmt.Sprintf(" (%s, code %d)", e.Category, e.Code)
+	case e.Category != "":
+		msg += fmt.Sprintf(" (%s)", e.Category)
+	case e.Code != 0:
+		msg += fmt.Sprintf(" (code %d)", e.Code)
+	}
+	if hint := e.Hint(); hint != "" {
+		msg += "\n" + hint
+	}
+	return msg
+}
+
+// Is reports whether e is a policy denial, so that
+// errors.Is(err, ErrPolicyDenied) matches categorized helper errors.
+func (e *HelperError) Is(target error) bool {
+	if target != ErrPolicyDenied {
+		return false
+	}
+	switch e.Category {
+	case protocol.ErrDeniedDenylist, protocol.ErrDeniedAllowlist, protocol.ErrHashMismatch, protocol.ErrSignatureInvalid:
+		return true
+	}
+	return false
+}
+
+// Hint returns guidance for fixing the error, or "" if there is none.
+func (e *HelperError) Hint() string {
+	switch e.Category {
+	case protocol.ErrDeniedDenylist:
+		return fmt.Sprintf("%q is on the hardcoded deny list of shell/exec bypass vectors and can never be launched through wstart.", e.Rule)
+	case protocol.ErrDeniedAllowlist:
+		if e.Rule != "" {
+			return fmt.Sprintf("The matching rule is %q. Extend it in %s, then run wstart-host.exe --sign-config.", e.Rule, e.Path)
+		}
+		return fmt.Sprintf("Add an [[allow]] rule for it to %s, then run wstart-host.exe --sign-config.", e.Path)
+	case protocol.ErrHashMismatch:
+		return fmt.Sprintf("The executable is not the one pinned by rule %q. If it was updated on purpose, run wstart-host.exe --pin <program> from an elevated PowerShell.", e.Rule)
+	case protocol.ErrSignatureInvalid:
+		return fmt.Sprintf("%s changed after it was signed. If the edit was yours, run wstart-host.exe --sign-config from an elevated PowerShell.", e.Path)
+	case protocol.ErrFileNotFound:
+		return fmt.Sprintf("Windows could not find %s. Check that it exists and is reachable from Windows.", e.Path)
+	case protocol.ErrNoAssociation:
+		return "No application is associated with this file type. Name a program explicitly, e.g. wstart notepad <file>."
+	case protocol.ErrNotWaitable:
+		return "The target was opened by an already running application, so there is no process to wait for. Drop -wait."
+	case protocol.ErrElevationCancelled:
+		return "The UAC prompt was cancelled."
+	}
+	return ""
+}
+
+// helperError converts a failed LaunchResponse into a *HelperError.
+func helperError(resp *protocol.LaunchResponse) *HelperError {
+	return &HelperError{
+		Category: resp.Category,
+		Message:  resp.Error,
+		Code:     resp.ErrCode,
+		Rule:     resp.Rule,
+		Path:     resp.Path,
+	}
+}
+
+// exitError attaches a reserved exit code to an error that originates in
+// wstart itself (see ExitCode).
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string { return e.err.Error() }
+func (e *exitError) Unwrap() error { return e.err }
+
+func withExit(code int, err error) error {
+	return &exitError{code: code, err: err}
+}
+
+// kindError tags an error with one of the sentinel errors above without
+// changing its message.
+type kindError struct {
+	kind error
+	err  error
+}
+
+func (e *kindError) Error() string   { return e.err.Error() }
+func (e *kindError) Unwrap() []error { return []error{e.kind, e.err} }
+
+func withKind(kind, err error) error {
+	return &kindError{kind: kind, err: err}
+}
+
+// interopFailure classifies the error from running a Windows process. If
+// ctx ended first, or the process never started, it returns an ErrInterop
+// error; if the process ran and exited unsuccessfully it returns nil and
+// the caller reports the failure itself.
+func interopFailure(ctx context.Context, what string, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return withKind(ErrInterop, fmt.Errorf("%s: %w", what, ctxErr))
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return nil
+	}
+	return withKind(ErrInterop, fmt.Errorf("%s: %w", what, err))
+}
+
+// ExitCode returns the process exit code for an error returned by Run,
+// RunEach or RunBatch: protocol.ExitDenied for policy denials,
+// protocol.ExitNotFound when the target does not exist, and
+// protocol.ExitInternal for everything else. Exit codes of launched
+// programs are returned in Result.ExitCode, never as errors.
+func ExitCode(err error) int {
+	var ee *exitError
+	if errors.As(err, &ee) {
+		return ee.code
+	}
+	var he *HelperError
+	if errors.As(err, &he) {
+		return protocol.ExitCodeFor(he.Category)
+	}
+	return protocol.ExitInternal
+}
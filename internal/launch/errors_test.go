@@ -0,0 +1,154 @@
+package launch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/sverrirab/wsl-host-start/internal/protocol"
+)
+
+func TestLaunchResultCategorizedError(t *testing.T) {
+	_, err := launchResult(&protocol.LaunchResponse{
+		Error:    `denied: "p4" subcommand "obliterate" is not allowed (allowed: edit, sync)`,
+		ErrCode:  5,
+		Category: protocol.ErrDeniedAllowlist,
+		Rule:     "p4 [edit, sync]",
+		Path:     `C:\Program Files\wstart\allowlist.toml`,
+	})
+
+	var he *HelperError
+	if !errors.As(err, &he) {
+		t.Fatalf("err = %T %v, want *HelperError", err, err)
+	}
+	if he.Category != protocol.ErrDeniedAllowlist || he.Rule != "p4 [edit, sync]" {
+		t.Errorf("HelperError = %+v", he)
+	}
+	msg := err.Error()
+	for _, want := range []string{"(denied-by-allowlist, code 5)", `"p4 [edit, sync]"`, `allowlist.toml`, "--sign-config"} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("message missing %q:\n%s", want, msg)
+		}
+	}
+}
+
+func TestHelperErrorHints(t *testing.T) {
+	tests := []struct {
+		err  HelperError
+		want string
+	}{
+		{HelperError{Category: protocol.ErrDeniedDenylist, Rule: "cmd"}, `"cmd" is on the hardcoded deny list`},
+		{HelperError{Category: protocol.ErrDeniedAllowlist, Path: "allowlist.toml"}, "Add an [[allow]] rule"},
+		{HelperError{Category: protocol.ErrHashMismatch, Rule: "p4 (sha256 pinned) (any args)"}, "wstart-host.exe --pin"},
+		{HelperError{Category: protocol.ErrSignatureInvalid, Path: "config.toml"}, "config.toml changed after it was signed"},
+		{HelperError{Category: protocol.ErrFileNotFound, Path: `C:\x.pdf`}, `could not find C:\x.pdf`},
+		{HelperError{Category: protocol.ErrNoAssociation}, "No application is associated"},
+		{HelperError{Category: protocol.ErrNotWaitable}, "Drop -wait"},
+		{HelperError{Category: protocol.ErrElevationCancelled}, "UAC prompt was cancelled"},
+		{HelperError{Category: protocol.ErrInternal}, ""},
+	}
+	for _, tt := range tests {
+		got := tt.err.Hint()
+		if tt.want == "" && got != "" || !strings.Contains(got, tt.want) {
+			t.Errorf("%s: Hint() = %q, want it to contain %q", tt.err.Category, got, tt.want)
+		}
+	}
+}
+
+// TestLaunchResultLegacyError checks responses from helpers that predate
+// categories keep their original message shape.
+func TestLaunchResultLegacyError(t *testing.T) {
+	_, err := launchResult(&protocol.LaunchResponse{Error: "ShellExecuteEx failed", ErrCode: 31})
+	if err == nil || err.Error() != "host helper: ShellExecuteEx failed (code 31)" {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"plain", errors.New("helper not found"), protocol.ExitInternal},
+		{"denied", &HelperError{Category: protocol.ErrDeniedAllowlist}, protocol.ExitDenied},
+		{"signature", &HelperError{Category: protocol.ErrSignatureInvalid}, protocol.ExitDenied},
+		{"not found", &HelperError{Category: protocol.ErrFileNotFound}, protocol.ExitNotFound},
+		{"legacy helper", &HelperError{Code: 2}, protocol.ExitInternal},
+		{"wrapped", fmt.Errorf("launching: %w", &HelperError{Category: protocol.ErrNoAssociation}), protocol.ExitNotFound},
+		{"reserved", withExit(protocol.ExitNotFound, errors.New("translating target path")), protocol.ExitNotFound},
+	}
+	for _, tt := range tests {
+		if got := ExitCode(tt.err); got != tt.want {
+			t.Errorf("%s: ExitCode = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSentinelErrors(t *testing.T) {
+	denied := fmt.Errorf("launching: %w", &HelperError{Category: protocol.ErrDeniedAllowlist})
+	if !errors.Is(denied, ErrPolicyDenied) {
+		t.Error("allowlist denial should match ErrPolicyDenied")
+	}
+	if errors.Is(&HelperError{Category: protocol.ErrFileNotFound}, ErrPolicyDenied) {
+		t.Error("file-not-found should not match ErrPolicyDenied")
+	}
+
+	hello := &protocol.HelloResponse{Version: "v1.0.0", Capabilities: []string{protocol.CapLaunch}}
+	err := requireCapabilities(hello, protocol.CapExec)
+	if !errors.Is(err, ErrVersionMismatch) {
+		t.Errorf("requireCapabilities err = %v, want ErrVersionMismatch", err)
+	}
+	if !strings.Contains(err.Error(), `does not support "exec"`) {
+		t.Errorf("message changed: %v", err)
+	}
+}
+
+func TestCapabilityUse(t *testing.T) {
+	for _, c := range protocol.Capabilities() {
+		if capabilityUse[c] == "" {
+			t.Errorf("capabilityUse has no entry for %q", c)
+		}
+	}
+}
+
+func TestFindHelperNotFound(t *testing.T) {
+	t.Setenv("WSTART_HOST_PATH", "")
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	_, err := findHelper(context.Background())
+	if !errors.Is(err, ErrHelperNotFound) {
+		t.Fatalf("err = %v, want ErrHelperNotFound", err)
+	}
+	if ExitCode(err) != protocol.ExitInternal {
+		t.Errorf("ExitCode = %d, want %d", ExitCode(err), protocol.ExitInternal)
+	}
+}
+
+func TestFindHelperCancelled(t *testing.T) {
+	t.Setenv("WSTART_HOST_PATH", "")
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := findHelper(ctx)
+	if !errors.Is(err, ErrInterop) || !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want ErrInterop wrapping context.Canceled", err)
+	}
+}
+
+func TestRequestDrivesFailure(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("WSTART_TEST_HELPER", "legacy") // exits 2 for --drives
+
+	tr := &helperTransport{path: testBinary(t), hello: &protocol.HelloResponse{Capabilities: []string{protocol.CapDrives}}}
+	_, err := tr.Request(context.Background(), &protocol.Request{Drives: true})
+	if !errors.Is(err, ErrInterop) {
+		t.Fatalf("err = %v, want ErrInterop", err)
+	}
+	if ExitCode(err) != protocol.ExitInternal {
+		t.Errorf("ExitCode = %d, want %d", ExitCode(err), protocol.ExitInternal)
+	}
+}
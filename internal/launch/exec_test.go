@@ -8,14 +8,18 @@ package launch
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/sverrirab/wsl-host-start/internal/protocol"
+	"github.com/sverrirab/wsl-host-start/internal/rpc"
 )
 
 // TestMain intercepts subprocess invocations before the test framework parses
@@ -34,7 +38,73 @@ func TestMain(m *testing.M) {
 //  1. Decode exactly one JSON LaunchRequest from stdin.
 //  2. Recover any bytes the decoder buffered past the JSON object.
 //  3. Perform the action dictated by mode.
+//
+// The handshake modes answer --hello / --version without reading stdin.
 func fakeHelper(mode string) {
+	switch mode {
+	case "hello":
+		fmt.Fprint(os.Stdout, `{"protocolVersion":1,"version":"v9.9.9","capabilities":["launch","drives"]}`)
+		os.Exit(0)
+	case "legacy":
+		// Mimics a pre-handshake helper: the flag package rejects --hello.
+		if len(os.Args) > 1 && os.Args[1] == "--version" {
+			fmt.Fprintln(os.Stdout, "v0.1.0")
+			os.Exit(0)
+		}
+		fmt.Fprintln(os.Stderr, "flag provided but not defined: -hello")
+		os.Exit(2)
+	case "serve":
+		// Mimics a helper with --serve: handshake via --hello, then JSON-RPC.
+		if len(os.Args) > 1 && os.Args[1] == "--hello" {
+			fmt.Fprint(os.Stdout, `{"protocolVersion":2,"version":"v9.9.9","capabilities":["launch","drives","serve"]}`)
+			os.Exit(0)
+		}
+		if err := rpc.Serve(os.Stdin, os.Stdout, fakeServe); err != nil {
+			fmt.Fprintln(os.Stderr, "fakeHelper: serve:", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	case "multi":
+		// Mimics a helper with the "multi" capability: --hello, then one
+		// --request carrying every target.
+		if len(os.Args) > 1 && os.Args[1] == "--hello" {
+			fmt.Fprint(os.Stdout, `{"protocolVersion":3,"version":"v9.9.9","capabilities":["launch","drives","request","multi"]}`)
+			os.Exit(0)
+		}
+		var req protocol.Request
+		if err := json.NewDecoder(os.Stdin).Decode(&req); err != nil {
+			fmt.Fprintln(os.Stderr, "fakeHelper: decode request:", err)
+			os.Exit(1)
+		}
+		resp := &protocol.Response{}
+		logLaunch("request")
+		for i, l := range req.Launches {
+			logLaunch(l.File)
+			if l.File == "missing.exe" {
+				resp.Launches = append(resp.Launches, protocol.LaunchResponse{Error: "file not found", ErrCode: 2, Category: protocol.ErrFileNotFound})
+			} else {
+				resp.Launches = append(resp.Launches, protocol.LaunchResponse{PID: i + 1})
+			}
+		}
+		_ = json.NewEncoder(os.Stdout).Encode(resp)
+		os.Exit(0)
+	case "combined":
+		// Mimics --request: answer hello, drives and launch in one response.
+		var req protocol.Request
+		if err := json.NewDecoder(os.Stdin).Decode(&req); err != nil || req.Launch == nil {
+			fmt.Fprintln(os.Stderr, "fakeHelper: decode request:", err)
+			os.Exit(1)
+		}
+		_ = json.NewEncoder(os.Stdout).Encode(&protocol.Response{
+			Hello: &protocol.HelloResponse{ProtocolVersion: 2, Version: "v9.9.9", Capabilities: req.Require},
+			Drives: &protocol.DrivesResponse{Drives: []protocol.DriveInfo{
+				{Letter: "P", Type: protocol.DriveSubst, Target: `C:\dev\workspace`},
+			}},
+			Launch: &protocol.LaunchResponse{PID: 7, ExitCode: len(req.Launch.File)},
+		})
+		os.Exit(0)
+	}
+
 	dec := json.NewDecoder(os.Stdin)
 	var req protocol.LaunchRequest
 	if err := dec.Decode(&req); err != nil {
@@ -56,6 +126,8 @@ func fakeHelper(mode string) {
 		}
 	case "exit-42":
 		os.Exit(42)
+	case "hang":
+		time.Sleep(time.Minute)
 	case "echo-file":
 		fmt.Fprint(os.Stdout, req.File)
 	default:
@@ -65,6 +137,38 @@ func fakeHelper(mode string) {
 	os.Exit(0)
 }
 
+// fakeServe answers --serve methods. Launching "missing.exe" fails, and
+// every launched file is appended to $WSTART_TEST_LOG.
+func fakeServe(method string, params json.RawMessage) (any, error) {
+	switch method {
+	case protocol.MethodHello:
+		return &protocol.HelloResponse{ProtocolVersion: 2, Capabilities: []string{"launch", "drives", "serve"}}, nil
+	case protocol.MethodDrives:
+		return &protocol.DrivesResponse{Drives: []protocol.DriveInfo{{Letter: "C", Type: protocol.DriveFixed}}}, nil
+	case protocol.MethodLaunch:
+		var req protocol.LaunchRequest
+		if err := json.Unmarshal(params, &req); err != nil {
+			return nil, rpc.InvalidParams(err)
+		}
+		logLaunch(req.File)
+		if req.File == "missing.exe" {
+			return &protocol.LaunchResponse{Error: "file not found", ErrCode: 2, Category: protocol.ErrFileNotFound}, nil
+		}
+		return &protocol.LaunchResponse{PID: 1}, nil
+	default:
+		return nil, rpc.MethodNotFound(method)
+	}
+}
+
+// logLaunch appends a line to $WSTART_TEST_LOG so tests can observe what
+// the fake helper was asked to do.
+func logLaunch(line string) {
+	if f, err := os.OpenFile(os.Getenv("WSTART_TEST_LOG"), os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0600); err == nil {
+		fmt.Fprintln(f, line)
+		f.Close()
+	}
+}
+
 // testBinary returns the path to the currently running test binary, which
 // doubles as the fake helper when WSTART_TEST_HELPER is set.
 func testBinary(t *testing.T) string {
@@ -87,7 +191,7 @@ func TestExecWithIOStdinForwarding(t *testing.T) {
 	want := "hello from wsl stdin"
 	var stdout, stderr bytes.Buffer
 
-	code, err := execWithIO(testBinary(t), testReq(), strings.NewReader(want), &stdout, &stderr)
+	code, err := execWithIO(context.Background(), testBinary(t), testReq(), strings.NewReader(want), &stdout, &stderr)
 	if err != nil {
 		t.Fatalf("execWithIO: %v (stderr: %s)", err, stderr.String())
 	}
@@ -107,7 +211,7 @@ func TestExecWithIOLargeStdin(t *testing.T) {
 	want := strings.Repeat("abcdefgh", 8*1024) // 64 KiB
 	var stdout, stderr bytes.Buffer
 
-	code, err := execWithIO(testBinary(t), testReq(), strings.NewReader(want), &stdout, &stderr)
+	code, err := execWithIO(context.Background(), testBinary(t), testReq(), strings.NewReader(want), &stdout, &stderr)
 	if err != nil {
 		t.Fatalf("execWithIO: %v", err)
 	}
@@ -124,7 +228,7 @@ func TestExecWithIOEmptyStdin(t *testing.T) {
 
 	var stdout, stderr bytes.Buffer
 
-	code, err := execWithIO(testBinary(t), testReq(), strings.NewReader(""), &stdout, &stderr)
+	code, err := execWithIO(context.Background(), testBinary(t), testReq(), strings.NewReader(""), &stdout, &stderr)
 	if err != nil {
 		t.Fatalf("execWithIO: %v", err)
 	}
@@ -142,7 +246,7 @@ func TestExecWithIOStderr(t *testing.T) {
 	want := "error output from child"
 	var stdout, stderr bytes.Buffer
 
-	code, err := execWithIO(testBinary(t), testReq(), strings.NewReader(want), &stdout, &stderr)
+	code, err := execWithIO(context.Background(), testBinary(t), testReq(), strings.NewReader(want), &stdout, &stderr)
 	if err != nil {
 		t.Fatalf("execWithIO: %v", err)
 	}
@@ -160,7 +264,7 @@ func TestExecWithIOStderr(t *testing.T) {
 func TestExecWithIOExitCode(t *testing.T) {
 	t.Setenv("WSTART_TEST_HELPER", "exit-42")
 
-	code, err := execWithIO(testBinary(t), testReq(), strings.NewReader(""), io.Discard, io.Discard)
+	code, err := execWithIO(context.Background(), testBinary(t), testReq(), strings.NewReader(""), io.Discard, io.Discard)
 	if err != nil {
 		t.Fatalf("execWithIO: %v", err)
 	}
@@ -169,6 +273,23 @@ func TestExecWithIOExitCode(t *testing.T) {
 	}
 }
 
+// TestExecWithIOContextTimeout verifies a hung helper is killed when the
+// context expires and reported as an interop failure, not a child exit code.
+func TestExecWithIOContextTimeout(t *testing.T) {
+	t.Setenv("WSTART_TEST_HELPER", "hang")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := execWithIO(ctx, testBinary(t), testReq(), strings.NewReader(""), io.Discard, io.Discard)
+	if !errors.Is(err, ErrInterop) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want ErrInterop wrapping DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("execWithIO returned after %v, want prompt cancellation", elapsed)
+	}
+}
+
 // TestExecWithIORequestFields verifies the JSON request is decoded correctly
 // by the helper (fakeHelper echoes req.File back on stdout via "echo-file").
 func TestExecWithIORequestFields(t *testing.T) {
@@ -182,7 +303,7 @@ func TestExecWithIORequestFields(t *testing.T) {
 	}
 	var stdout, stderr bytes.Buffer
 
-	code, err := execWithIO(testBinary(t), req, strings.NewReader(""), &stdout, &stderr)
+	code, err := execWithIO(context.Background(), testBinary(t), req, strings.NewReader(""), &stdout, &stderr)
 	if err != nil {
 		t.Fatalf("execWithIO: %v (stderr: %s)", err, stderr.String())
 	}
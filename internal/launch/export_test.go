@@ -8,3 +8,6 @@ var CheckConfigReport = checkConfigReport
 
 // ConfigReport re-exports configReport for test assertions.
 type ConfigReport = configReport
+
+// ProgramEnv re-exports programEnv for test assertions.
+type ProgramEnv = programEnv
@@ -0,0 +1,78 @@
+package launch
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/sverrirab/wsl-host-start/internal/drivecache"
+	"github.com/sverrirab/wsl-host-start/internal/protocol"
+)
+
+// hostStateFile caches helper discovery and the protocol handshake between
+// runs, so a steady-state launch spawns no cmd.exe and no --hello.
+const hostStateFile = "helper.json"
+
+// hostState is invalidated whenever the helper binary changes size or
+// modification time (i.e. after an upgrade).
+type hostState struct {
+	HelperPath string                  `json:"helperPath"`
+	Size       int64                   `json:"size"`
+	ModTime    time.Time               `json:"modTime"`
+	Hello      *protocol.HelloResponse `json:"hello,omitempty"`
+}
+
+func hostStatePath() string {
+	return filepath.Join(drivecache.Dir(), hostStateFile)
+}
+
+// loadHostState returns the cached state if the helper it describes still
+// exists unchanged on disk, or nil otherwise.
+func loadHostState() *hostState {
+	data, err := os.ReadFile(hostStatePath())
+	if err != nil {
+		return nil
+	}
+	var st hostState
+	if err := json.Unmarshal(data, &st); err != nil {
+		return nil
+	}
+	fi, err := os.Stat(st.HelperPath)
+	if err != nil || fi.Size() != st.Size || !fi.ModTime().Equal(st.ModTime) {
+		return nil
+	}
+	return &st
+}
+
+// saveHostState records helperPath (and hello, if known) for later runs.
+// Failures are ignored: the cache only saves time.
+func saveHostState(helperPath string, hello *protocol.HelloResponse) {
+	fi, err := os.Stat(helperPath)
+	if err != nil {
+		return
+	}
+	st := hostState{
+		HelperPath: helperPath,
+		Size:       fi.Size(),
+		ModTime:    fi.ModTime(),
+		Hello:      hello,
+	}
+	data, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(drivecache.Dir(), 0700); err != nil {
+		return
+	}
+	_ = os.WriteFile(hostStatePath(), data, 0600)
+}
+
+// cachedHello returns the handshake result cached for helperPath, or nil.
+func cachedHello(helperPath string) *protocol.HelloResponse {
+	st := loadHostState()
+	if st == nil || st.HelperPath != helperPath {
+		return nil
+	}
+	return st.Hello
+}
@@ -0,0 +1,95 @@
+package launch
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sverrirab/wsl-host-start/internal/drivecache"
+	"github.com/sverrirab/wsl-host-start/internal/protocol"
+)
+
+func TestHostStateRoundTrip(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	helper := filepath.Join(t.TempDir(), "wstart-host.exe")
+	if err := os.WriteFile(helper, []byte("v1"), 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	if cachedHello(helper) != nil {
+		t.Fatal("expected no cached handshake before saving")
+	}
+
+	saveHostState(helper, &protocol.HelloResponse{ProtocolVersion: 2, Version: "v1.0.0"})
+	st := loadHostState()
+	if st == nil || st.HelperPath != helper {
+		t.Fatalf("loadHostState = %+v, want helper %s", st, helper)
+	}
+	if h := cachedHello(helper); h == nil || h.Version != "v1.0.0" {
+		t.Errorf("cachedHello = %+v", h)
+	}
+	if cachedHello("/elsewhere/wstart-host.exe") != nil {
+		t.Error("handshake cached for a different helper path should not be returned")
+	}
+}
+
+// TestHostStateInvalidatedOnUpgrade verifies that replacing the helper binary
+// forces discovery and the handshake to run again.
+func TestHostStateInvalidatedOnUpgrade(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	helper := filepath.Join(t.TempDir(), "wstart-host.exe")
+	if err := os.WriteFile(helper, []byte("v1"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	saveHostState(helper, &protocol.HelloResponse{Version: "v1.0.0"})
+
+	if err := os.WriteFile(helper, []byte("v2 is bigger"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(helper, later, later); err != nil {
+		t.Fatal(err)
+	}
+
+	if st := loadHostState(); st != nil {
+		t.Errorf("expected stale state after upgrade, got %+v", st)
+	}
+}
+
+func TestInvokeCombined(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("WSTART_TEST_HELPER", "combined")
+	helper := testBinary(t)
+
+	req := &protocol.Request{
+		Require: []string{protocol.CapLaunch, protocol.CapRequest},
+		Hello:   true,
+		Drives:  true,
+		Launch:  &protocol.LaunchRequest{File: "notepad.exe"},
+	}
+	combined, err := invokeCombined(context.Background(), helper, req, false)
+	if err != nil {
+		t.Fatalf("invokeCombined: %v", err)
+	}
+	resp := combined.Launch
+	if resp == nil {
+		t.Fatal("no launch result")
+	}
+	if resp.PID != 7 || resp.ExitCode != len("notepad.exe") {
+		t.Errorf("launch response = %+v", resp)
+	}
+
+	// The handshake and drive table from the response are cached.
+	if h := cachedHello(helper); h == nil || !h.Has(protocol.CapRequest) {
+		t.Errorf("cachedHello = %+v, want request capability", h)
+	}
+	drv, err := drivecache.New(0).Cached()
+	if err != nil {
+		t.Fatalf("drive cache not written: %v", err)
+	}
+	if len(drv.Drives) != 1 || drv.Drives[0].Letter != "P" {
+		t.Errorf("cached drives = %+v", drv.Drives)
+	}
+}
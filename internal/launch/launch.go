@@ -4,23 +4,29 @@ package launch
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
 	"strings"
 
+	"github.com/sverrirab/wsl-host-start/internal/allowlist"
 	"github.com/sverrirab/wsl-host-start/internal/config"
 	"github.com/sverrirab/wsl-host-start/internal/drivecache"
+	"github.com/sverrirab/wsl-host-start/internal/envpolicy"
 	"github.com/sverrirab/wsl-host-start/internal/interop"
 	"github.com/sverrirab/wsl-host-start/internal/pathconv"
 	"github.com/sverrirab/wsl-host-start/internal/protocol"
 )
 
-// Version is set by the WSL CLI so the launch package can verify
-// that the host helper matches.
+// Version is set by the WSL CLI and reported alongside the helper's
+// version in diagnostics.
 var Version string
 
 // Options holds the parsed CLI flags.
@@ -33,16 +39,123 @@ type Options struct {
 	Wait    bool
 	DryRun  bool
 	Verbose bool
+	Timing  bool
+
+	// RawArgs passes Args through without translating paths in them.
+	RawArgs bool
+
+	// CmdLine, if set, is passed to the program verbatim instead of Args,
+	// for programs that parse their command line in their own way.
+	CmdLine string
+
+	// Env holds variables set for this launch only (wstart -env). They are
+	// forwarded whether or not the forward list names them, unless they
+	// are blocked.
+	Env map[string]string
+
+	// Ext is the extension of the temp file written when Target is
+	// StdinTarget (default ".txt"); it picks the program that opens it.
+	Ext string
+
+	// Transport, if set, replaces the spawned wstart-host.exe. Helper
+	// discovery and the on-disk caches are skipped, and config is read
+	// from ConfigDir (defaults only if empty).
+	Transport Transport
+	ConfigDir string
+
+	// Stdin, Stdout and Stderr are wired to the program in -wait mode and
+	// default to the process's own.
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
 }
 
 // Result holds the outcome of a launch.
 type Result struct {
 	ExitCode int
 	PID      int
+
+	// Targets holds per-target outcomes for multi-target launches (RunEach).
+	Targets []TargetResult
+}
+
+// TargetResult is the outcome for one target of a multi-target launch.
+type TargetResult struct {
+	Target string
+	PID    int
+	Err    error
 }
 
 // Run executes the full launch workflow.
+//
+// In the steady state this spawns exactly one Windows process: helper
+// discovery and the protocol handshake are cached in ~/.cache/wstart, and
+// when the drive cache is stale the helper applies aliases itself and
+// returns a fresh drive table in the same --request exchange.
 func Run(opts *Options) (*Result, error) {
+	return RunContext(context.Background(), opts)
+}
+
+// RunContext is like Run, but every Windows process it starts (helper
+// discovery, wslpath, --drives, --request, --launch, --exec) is killed if
+// ctx is done first. Errors can be classified with errors.Is against
+// ErrHelperNotFound, ErrVersionMismatch, ErrPolicyDenied and ErrInterop,
+// or with errors.As against *HelperError.
+func RunContext(ctx context.Context, opts *Options) (*Result, error) {
+	sw := newStopwatch(opts.Timing)
+	defer sw.total()
+
+	p, err := newPipeline(ctx, opts, sw, nil)
+	if err != nil {
+		return nil, err
+	}
+	target := opts.Target
+	if target == StdinTarget {
+		if target, err = p.stdinFile(); err != nil {
+			return nil, err
+		}
+		p.stdinDoc = true
+		// Once the program has exited (or nothing was launched), the
+		// file is no longer needed; otherwise a later run removes it.
+		if opts.Wait || opts.DryRun {
+			defer os.Remove(target)
+		}
+	}
+	req, err := p.request(target, opts.Args)
+	if err != nil {
+		return nil, err
+	}
+	return p.launch(req)
+}
+
+// pipeline holds the state shared by every target launched in one run:
+// the helper and its handshake, config, path converter and working directory.
+type pipeline struct {
+	ctx           context.Context
+	opts          *Options
+	sw            *stopwatch
+	helperPath    string
+	hello         *protocol.HelloResponse
+	cfg           *config.Config
+	conv          *pathconv.Converter
+	workDir       string
+	resolveOnHost bool
+	transport     Transport
+
+	// stdinDoc is set when the target is a document written from stdin,
+	// which only ShellExecuteEx can open.
+	stdinDoc bool
+
+	// session is set when launching a batch over wstart-host --serve.
+	session *Session
+}
+
+// newPipeline locates the helper, completes the handshake, loads config and
+// prepares path translation. If open is non-nil and the helper supports
+// --serve, it is called to start a session that later requests use.
+func newPipeline(ctx context.Context, opts *Options, sw *stopwatch, open func(ctx context.Context, helperPath string) (*Session, error)) (*pipeline, error) {
+	p := &pipeline{ctx: ctx, opts: opts, sw: sw}
+
 	// 1. Detect WSL interop (non-fatal — allows running in degraded environments).
 	info, err := interop.Detect()
 	if err != nil {
@@ -53,138 +166,421 @@ func Run(opts *Options) (*Result, error) {
 		fmt.Fprintf(os.Stderr, "WSL version: %d, distro: %s\n", info.WSLVersion, info.DistroName)
 	}
 
-	// 2. Locate helper binary and verify version.
-	helperPath, err := findHelper()
+	// 2. Locate helper binary and negotiate the protocol (cached per helper binary).
+	if opts.Transport != nil {
+		if err := p.useTransport(opts.Transport); err != nil {
+			return nil, err
+		}
+		return p, nil
+	}
+	p.helperPath, err = findHelper(ctx)
 	if err != nil {
 		return nil, err
 	}
 	if opts.Verbose {
-		fmt.Fprintf(os.Stderr, "Helper: %s\n", helperPath)
+		fmt.Fprintf(os.Stderr, "Helper: %s\n", p.helperPath)
 	}
-	if err := checkHelperVersion(helperPath, opts.Verbose); err != nil {
-		return nil, err
+	sw.lap("find helper")
+
+	p.hello = cachedHello(p.helperPath)
+	if p.hello == nil {
+		p.hello, err = negotiate(ctx, p.helperPath, opts.Verbose)
+		if err != nil {
+			return nil, err
+		}
+		saveHostState(p.helperPath, p.hello)
+		sw.lap("handshake")
+	} else if opts.Verbose {
+		fmt.Fprintf(os.Stderr, "Version: wstart=%s, wstart-host=%s (protocol %d, cached)\n",
+			Version, p.hello.Version, p.hello.ProtocolVersion)
+	}
+	p.transport = &helperTransport{path: p.helperPath, hello: p.hello, verbose: opts.Verbose}
+
+	if open != nil && p.hello.Has(protocol.CapServe) {
+		p.session, err = open(ctx, p.helperPath)
+		if err != nil {
+			return nil, err
+		}
+		sw.lap("open session")
 	}
 
 	// 3. Load config from the helper's directory.
-	helperDir := filepath.Dir(helperPath)
-	cfg, err := config.Load(helperDir)
-	if err != nil {
-		return nil, fmt.Errorf("loading config: %w", err)
+	if err := p.prepare(filepath.Dir(p.helperPath)); err != nil {
+		p.close()
+		return nil, err
 	}
+	return p, nil
+}
 
-	// 4. Get drive mappings.
-	var drives []protocol.DriveInfo
-	if cfg.Drives.AutoDetect {
-		cache := drivecache.New(0)
-		resp, cacheErr := cache.Get(helperPath)
-		if cacheErr != nil {
-			if opts.Verbose {
-				fmt.Fprintf(os.Stderr, "Drive cache: %v (continuing without aliases)\n", cacheErr)
-			}
-		} else {
-			drives = resp.Drives
-			if opts.Verbose {
-				for _, d := range drives {
-					if d.Type == protocol.DriveSubst {
-						fmt.Fprintf(os.Stderr, "Subst: %s: → %s\n", d.Letter, d.Target)
-					}
-				}
-			}
-		}
+// useTransport completes newPipeline for a caller-supplied transport: the
+// handshake goes through it, and no helper-specific caches are used.
+func (p *pipeline) useTransport(t Transport) error {
+	p.transport = t
+	resp, err := p.send(&protocol.Request{Hello: true})
+	if err != nil {
+		return err
 	}
+	if resp.Hello == nil {
+		return fmt.Errorf("host helper returned no handshake")
+	}
+	p.hello = resp.Hello
+	p.sw.lap("handshake")
+	return p.prepare(p.opts.ConfigDir)
+}
 
-	// 5. Translate paths.
-	conv := pathconv.NewConverter(drives, cfg.Drives.Aliases, cfg.Drives.PreferAliases)
+// prepare loads config from configDir, builds the path converter and
+// translates the working directory once for all targets.
+func (p *pipeline) prepare(configDir string) error {
+	opts, ctx := p.opts, p.ctx
+	var err error
 
-	winTarget, err := conv.ToWindows(opts.Target)
+	p.cfg, err = config.Load(configDir)
 	if err != nil {
-		return nil, fmt.Errorf("translating target path: %w", err)
+		return fmt.Errorf("loading config: %w", err)
 	}
 
-	winWorkDir := opts.WorkDir
-	if winWorkDir != "" {
-		winWorkDir, err = conv.ToWindows(winWorkDir)
+	// 4. Get drive mappings and build the path converter.
+	p.conv = pathconv.NewConverter(p.loadDrives(), p.cfg.Drives.Aliases, p.cfg.Drives.PreferAliases)
+
+	// 5. Translate the working directory once for all targets.
+	if opts.WorkDir != "" {
+		p.workDir, err = p.conv.ToWindowsContext(ctx, opts.WorkDir)
 		if err != nil {
-			return nil, fmt.Errorf("translating working directory: %w", err)
+			return fmt.Errorf("translating working directory: %w", err)
 		}
 	} else {
 		// Default working directory: translate current WSL cwd.
 		cwd, _ := os.Getwd()
-		winWorkDir, err = conv.ToWindows(cwd)
+		p.workDir, err = p.conv.ToWindowsContext(ctx, cwd)
 		if err != nil {
 			if opts.Verbose {
 				fmt.Fprintf(os.Stderr, "Could not translate cwd %q: %v\n", cwd, err)
 			}
-			winWorkDir = ""
+			p.workDir = ""
+		}
+	}
+	return nil
+}
+
+// loadDrives returns the drive table used for alias resolution. A stale
+// cache is refreshed over the session if one is open; otherwise the
+// combined request resolves aliases on the host, and only helpers without
+// --request get a separate --drives call.
+func (p *pipeline) loadDrives() []protocol.DriveInfo {
+	if !p.cfg.Drives.AutoDetect {
+		return nil
+	}
+	if !p.hello.Has(protocol.CapDrives) {
+		if p.opts.Verbose {
+			fmt.Fprintf(os.Stderr, "Drive detection: helper lacks %q capability (continuing without aliases)\n", protocol.CapDrives)
+		}
+		return nil
+	}
+	defer p.sw.lap("drive cache")
+
+	// Caller-supplied transports answer for themselves; the drive cache
+	// describes the installed helper.
+	var resp *protocol.DrivesResponse
+	cacheErr := errors.New("not used with a custom transport")
+	cache := drivecache.New(0)
+	if p.helperPath != "" {
+		resp, cacheErr = cache.Cached()
+	}
+	switch {
+	case cacheErr == nil:
+	case p.session != nil:
+		resp, cacheErr = p.session.Drives()
+		if cacheErr == nil {
+			_ = cache.Store(resp)
+		}
+	case p.hello.Has(protocol.CapRequest):
+		// The helper resolves aliases and returns a fresh drive
+		// table in the same round trip as the launch.
+		p.resolveOnHost = true
+	default:
+		var full *protocol.Response
+		if full, cacheErr = p.send(&protocol.Request{Drives: true}); cacheErr == nil {
+			if resp = full.Drives; resp == nil {
+				cacheErr = errors.New("host helper returned no drive table")
+			}
 		}
 	}
 
-	// 6. Build launch request.
-	verb := opts.Verb
+	if cacheErr != nil {
+		if p.opts.Verbose {
+			if p.resolveOnHost {
+				fmt.Fprintf(os.Stderr, "Drive cache: %v (helper will resolve aliases)\n", cacheErr)
+			} else {
+				fmt.Fprintf(os.Stderr, "Drive cache: %v (continuing without aliases)\n", cacheErr)
+			}
+		}
+		return nil
+	}
+	if p.opts.Verbose {
+		for _, d := range resp.Drives {
+			if d.Type == protocol.DriveSubst {
+				fmt.Fprintf(os.Stderr, "Subst: %s: → %s\n", d.Letter, d.Target)
+			}
+		}
+	}
+	return resp.Drives
+}
+
+// request translates target and builds the launch request for it.
+func (p *pipeline) request(target string, args []string) (*protocol.LaunchRequest, error) {
+	if target == StdinTarget {
+		return nil, errStdinTarget
+	}
+	winTarget, err := p.conv.ToWindowsContext(p.ctx, target)
+	if err != nil {
+		err = fmt.Errorf("translating target path: %w", err)
+		if _, serr := os.Stat(target); errors.Is(serr, fs.ErrNotExist) {
+			return nil, withExit(protocol.ExitNotFound, err)
+		}
+		return nil, err
+	}
+	p.sw.lap("translate")
+
+	if p.opts.CmdLine != "" && len(args) > 0 {
+		return nil, fmt.Errorf("a raw command line cannot be combined with program arguments")
+	}
+	args, err = p.translateArgs(target, args)
+	if err != nil {
+		return nil, err
+	}
+
+	verb := p.opts.Verb
 	if verb == "" {
-		verb = cfg.Defaults.Verb
+		verb = p.cfg.Defaults.Verb
 	}
-	show := opts.Show
+	show := p.opts.Show
 	if show == "" {
-		show = cfg.Defaults.Show
+		show = p.cfg.Defaults.Show
+	}
+
+	program := allowlist.ProgramName(target)
+	adHoc, err := adHocEnv(p.cfg.Env.ForProgram(program), p.opts.Env)
+	if err != nil {
+		return nil, err
+	}
+	env := collectEnvVars(p.ctx, p.cfg, program, p.conv)
+	if len(adHoc) > 0 && env == nil {
+		env = make(map[string]string, len(adHoc))
+	}
+	for _, name := range adHoc {
+		env[name] = p.opts.Env[name]
 	}
 
-	req := protocol.LaunchRequest{
-		File:    winTarget,
-		Verb:    verb,
-		Args:    opts.Args,
-		WorkDir: winWorkDir,
-		Show:    show,
-		Wait:    opts.Wait,
-		EnvVars: collectEnvVars(cfg),
+	req := &protocol.LaunchRequest{
+		File:           winTarget,
+		Verb:           verb,
+		Args:           args,
+		CmdLine:        p.opts.CmdLine,
+		WorkDir:        p.workDir,
+		Show:           show,
+		Wait:           p.opts.Wait,
+		EnvVars:        env,
+		AdHocEnv:       adHoc,
+		ResolveAliases: p.resolveOnHost,
 	}
 
-	if opts.Verbose {
+	if p.opts.Verbose {
 		fmt.Fprintf(os.Stderr, "Request: file=%q verb=%q workDir=%q wait=%v\n",
 			req.File, req.Verb, req.WorkDir, req.Wait)
 		if len(req.Args) > 0 {
 			fmt.Fprintf(os.Stderr, "  args: %v\n", req.Args)
 		}
+		if req.CmdLine != "" {
+			fmt.Fprintf(os.Stderr, "  cmdline: %s\n", req.CmdLine)
+		}
 		if len(req.EnvVars) > 0 {
-			fmt.Fprintf(os.Stderr, "  env: %v\n", req.EnvVars)
+			fmt.Fprintf(os.Stderr, "  env: %v\n", envpolicy.Redact(req.EnvVars))
 		}
 	}
+	return req, nil
+}
 
-	if opts.DryRun {
-		data, _ := json.MarshalIndent(req, "", "  ")
-		fmt.Println(string(data))
+// launch sends req to the helper and returns the outcome.
+func (p *pipeline) launch(req *protocol.LaunchRequest) (*Result, error) {
+	if p.opts.DryRun {
+		shown := *req
+		shown.EnvVars = envpolicy.Redact(req.EnvVars)
+		data, _ := json.MarshalIndent(&shown, "", "  ")
+		fmt.Fprintln(p.stdout(), string(data))
 		return &Result{}, nil
 	}
 
-	// 7. Invoke helper.
 	// Use --exec mode for wait+open: stdio passthrough for console programs.
-	// Use --launch mode (ShellExecuteEx) for everything else.
-	if opts.Wait && (verb == "open" || verb == "") {
-		exitCode, err := invokeHelperExec(helperPath, &req)
+	// Sessions own stdin/stdout, so batches always go through ShellExecuteEx,
+	// as do documents written from stdin: --exec cannot open a document.
+	// Otherwise use a combined request (--request, or --launch for older helpers).
+	useExec := p.session == nil && !p.stdinDoc && req.Wait && (req.Verb == "open" || req.Verb == "")
+	required := []string{protocol.CapLaunch}
+	if useExec {
+		required = []string{protocol.CapExec}
+	}
+	if len(req.EnvVars) > 0 {
+		required = append(required, protocol.CapEnvVars)
+	}
+	if len(req.AdHocEnv) > 0 {
+		required = append(required, protocol.CapAdHocEnv)
+	}
+	if req.CmdLine != "" {
+		required = append(required, protocol.CapCmdLine)
+	}
+	if err := requireCapabilities(p.hello, required...); err != nil {
+		return nil, err
+	}
+	if !useExec {
+		p.warnLaunchEnv(req)
+	}
+
+	if useExec {
+		defer p.sw.lap("helper --exec")
+		exitCode, err := p.transport.Exec(p.ctx, req, p.stdin(), p.stdout(), p.stderr())
 		if err != nil {
 			return nil, err
 		}
 		return &Result{ExitCode: exitCode}, nil
 	}
 
-	resp, err := invokeHelper(helperPath, &req)
+	var resp *protocol.LaunchResponse
+	var err error
+	switch {
+	case p.session != nil:
+		resp, err = p.session.Launch(req)
+		p.sw.lap("session launch")
+	default:
+		var combined *protocol.Response
+		combined, err = p.send(&protocol.Request{
+			Require: required,
+			Hello:   true,
+			Drives:  req.ResolveAliases,
+			Launch:  req,
+		})
+		if err == nil {
+			resp = combined.Launch
+			if resp == nil {
+				err = fmt.Errorf("host helper returned no launch result")
+			}
+		}
+		p.sw.lap("helper request")
+	}
 	if err != nil {
 		return nil, err
 	}
+	p.warnDroppedEnv(resp)
+	return launchResult(resp)
+}
 
-	if resp.Error != "" {
-		return nil, fmt.Errorf("host helper: %s (code %d)", resp.Error, resp.ErrCode)
+// launchMulti sends several requests to the helper in one --request
+// exchange and returns one response per request, in order.
+func (p *pipeline) launchMulti(reqs []protocol.LaunchRequest) ([]protocol.LaunchResponse, error) {
+	required := []string{protocol.CapLaunch, protocol.CapMulti}
+	resolve := false
+	for _, r := range reqs {
+		if len(r.EnvVars) > 0 && !slices.Contains(required, protocol.CapEnvVars) {
+			required = append(required, protocol.CapEnvVars)
+		}
+		if r.CmdLine != "" && !slices.Contains(required, protocol.CapCmdLine) {
+			required = append(required, protocol.CapCmdLine)
+		}
+		if len(r.AdHocEnv) > 0 && !slices.Contains(required, protocol.CapAdHocEnv) {
+			required = append(required, protocol.CapAdHocEnv)
+		}
+		resolve = resolve || r.ResolveAliases
+	}
+	if err := requireCapabilities(p.hello, required...); err != nil {
+		return nil, err
+	}
+	for i := range reqs {
+		if p.warnLaunchEnv(&reqs[i]) {
+			break
+		}
 	}
 
+	defer p.sw.lap("helper request")
+	resp, err := p.send(&protocol.Request{
+		Require:  required,
+		Hello:    true,
+		Drives:   resolve,
+		Launches: reqs,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Launches) != len(reqs) {
+		return nil, fmt.Errorf("host helper returned %d launch results for %d targets", len(resp.Launches), len(reqs))
+	}
+	return resp.Launches, nil
+}
+
+// launchResult converts a helper response into a Result, or a
+// *HelperError if the helper reported one.
+func launchResult(resp *protocol.LaunchResponse) (*Result, error) {
+	if resp.Error != "" {
+		return nil, helperError(resp)
+	}
 	return &Result{
 		ExitCode: resp.ExitCode,
 		PID:      resp.PID,
 	}, nil
 }
 
+// warnLaunchEnv warns, and returns true, if req forwards variables that
+// the helper would silently ignore: helpers without the "launchEnv"
+// capability only apply them in --exec mode.
+func (p *pipeline) warnLaunchEnv(req *protocol.LaunchRequest) bool {
+	if len(req.EnvVars) == 0 || p.hello.Has(protocol.CapLaunchEnv) {
+		return false
+	}
+	fmt.Fprintf(p.stderr(), "wstart: warning: wstart-host.exe %s only forwards [env] variables with -wait; upgrade it to forward them here\n", p.hello.Version)
+	return true
+}
+
+// warnDroppedEnv reports the variables the helper refused to forward. It
+// only drops variables that wstart itself would not send, so this points
+// at a config mismatch between the two sides.
+func (p *pipeline) warnDroppedEnv(resp *protocol.LaunchResponse) {
+	for _, d := range resp.DroppedEnv {
+		fmt.Fprintf(p.stderr(), "wstart: warning: wstart-host did not forward %s\n", d)
+	}
+}
+
+func (p *pipeline) stdin() io.Reader {
+	if p.opts.Stdin != nil {
+		return p.opts.Stdin
+	}
+	return os.Stdin
+}
+
+func (p *pipeline) stdout() io.Writer {
+	if p.opts.Stdout != nil {
+		return p.opts.Stdout
+	}
+	return os.Stdout
+}
+
+func (p *pipeline) stderr() io.Writer {
+	if p.opts.Stderr != nil {
+		return p.opts.Stderr
+	}
+	return os.Stderr
+}
+
+// close ends the helper session, if any.
+func (p *pipeline) close() error {
+	if p.session == nil {
+		return nil
+	}
+	err := p.session.Close()
+	p.session = nil
+	return err
+}
+
 // RefreshDrives forces a drive cache refresh and prints the results.
 func RefreshDrives() error {
-	helperPath, err := findHelper()
+	helperPath, err := findHelper(context.Background())
 	if err != nil {
 		return err
 	}
@@ -192,7 +588,7 @@ func RefreshDrives() error {
 	cache := drivecache.New(0)
 	resp, err := cache.Refresh(helperPath)
 	if err != nil {
-		return err
+		return withKind(ErrInterop, err)
 	}
 
 	for _, d := range resp.Drives {
@@ -208,59 +604,161 @@ func RefreshDrives() error {
 	return nil
 }
 
-// checkHelperVersion invokes the helper with --version and compares against
-// the WSL CLI version. Returns an error if they don't match.
-func checkHelperVersion(helperPath string, verbose bool) error {
-	if Version == "" || Version == "dev" {
-		return nil // Skip check for dev builds.
+// ToWSL converts a Windows path to a WSL path, expanding the drive aliases
+// known from config and the drive cache (wstart -to-wsl).
+func ToWSL(winPath string) (string, error) {
+	ctx := context.Background()
+	conv, err := newConverter(ctx)
+	if err != nil {
+		return "", err
 	}
+	return conv.ToWSLContext(ctx, winPath)
+}
 
-	out, err := exec.Command(helperPath, "--version").Output()
+// newConverter builds a path converter outside a launch, from the helper's
+// config and drive table. Without a helper it still translates, but knows
+// no aliases. If ctx ends while the drive table is read, the error is an
+// ErrInterop one.
+func newConverter(ctx context.Context) (*pathconv.Converter, error) {
+	helperPath, err := findHelper(ctx)
+	if err != nil {
+		return pathconv.NewConverter(nil, nil, false), nil
+	}
+	cfg, err := config.Load(filepath.Dir(helperPath))
 	if err != nil {
-		return fmt.Errorf("querying host helper version: %w", err)
+		return nil, fmt.Errorf("loading config: %w", err)
+	}
+	var drives []protocol.DriveInfo
+	if cfg.Drives.AutoDetect {
+		cache := drivecache.New(0)
+		resp, err := cache.Cached()
+		if err != nil {
+			resp, err = cache.RefreshContext(ctx, helperPath)
+			if ctx.Err() != nil {
+				return nil, withKind(ErrInterop, err)
+			}
+		}
+		if err == nil {
+			drives = resp.Drives
+		}
+	}
+	return pathconv.NewConverter(drives, cfg.Drives.Aliases, cfg.Drives.PreferAliases), nil
+}
+
+// negotiate performs the protocol handshake with the helper. Helpers that
+// predate --hello are identified via --version and treated as protocol 0.
+func negotiate(ctx context.Context, helperPath string, verbose bool) (*protocol.HelloResponse, error) {
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, helperPath, "--hello")
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+
+	var hello *protocol.HelloResponse
+	switch {
+	case err == nil:
+		hello = &protocol.HelloResponse{}
+		if err := json.Unmarshal(out, hello); err != nil {
+			return nil, fmt.Errorf("decoding host helper handshake: %w (raw: %s)", err, out)
+		}
+	case strings.Contains(stderr.String(), "flag provided but not defined"):
+		out, err := exec.CommandContext(ctx, helperPath, "--version").Output()
+		if err != nil {
+			if ierr := interopFailure(ctx, "querying host helper version", err); ierr != nil {
+				return nil, ierr
+			}
+			return nil, fmt.Errorf("querying host helper version: %w", err)
+		}
+		hello = protocol.LegacyHello(strings.TrimSpace(string(out)))
+	default:
+		if ierr := interopFailure(ctx, "querying host helper", err); ierr != nil {
+			return nil, ierr
+		}
+		return nil, fmt.Errorf("querying host helper: %w", err)
 	}
-	hostVersion := strings.TrimSpace(string(out))
 
 	if verbose {
-		fmt.Fprintf(os.Stderr, "Version: wstart=%s, wstart-host=%s\n", Version, hostVersion)
+		fmt.Fprintf(os.Stderr, "Version: wstart=%s, wstart-host=%s (protocol %d, capabilities: %s)\n",
+			Version, hello.Version, hello.ProtocolVersion, strings.Join(hello.Capabilities, ", "))
 	}
 
-	if hostVersion != Version {
-		return fmt.Errorf("version mismatch: wstart %s, wstart-host.exe %s\n"+
-			"Download the latest release and run wstart-host.exe --install", Version, hostVersion)
+	if hello.ProtocolVersion < protocol.MinProtocolVersion {
+		return nil, withKind(ErrVersionMismatch, fmt.Errorf("wstart-host.exe %s speaks protocol %d, but wstart %s needs at least %d\n"+
+			"Download the latest release and run wstart-host.exe --install",
+			hello.Version, hello.ProtocolVersion, Version, protocol.MinProtocolVersion))
 	}
-	return nil
+	return hello, nil
 }
 
-func findHelper() (string, error) {
+// capabilityUse describes what each capability is needed for, for error messages.
+var capabilityUse = map[string]string{
+	protocol.CapLaunch:    "launching via ShellExecuteEx",
+	protocol.CapExec:      "-wait with console passthrough",
+	protocol.CapDrives:    "drive alias detection",
+	protocol.CapEnvVars:   "forwarding [env] variables",
+	protocol.CapRequest:   "combined requests and helper-side drive aliases",
+	protocol.CapServe:     "a persistent helper session",
+	protocol.CapMulti:     "-each and -batch over one request",
+	protocol.CapCmdLine:   "-cmdline",
+	protocol.CapLaunchEnv: "forwarding [env] variables to ShellExecuteEx launches",
+	protocol.CapAdHocEnv:  "-env and -env-from",
+}
+
+// requireCapabilities returns an error naming the first required capability
+// the helper does not advertise.
+func requireCapabilities(hello *protocol.HelloResponse, required ...string) error {
+	missing := hello.Missing(required...)
+	if len(missing) == 0 {
+		return nil
+	}
+	return withKind(ErrVersionMismatch, fmt.Errorf("wstart-host.exe %s does not support %q (needed for %s)\n"+
+		"Download the latest release and run wstart-host.exe --install",
+		hello.Version, missing[0], capabilityUse[missing[0]]))
+}
+
+// FindHelper locates wstart-host.exe: $WSTART_HOST_PATH, then the location
+// cached by a previous run, then the well-known install directories.
+func FindHelper(ctx context.Context) (string, error) {
+	return findHelper(ctx)
+}
+
+func findHelper(ctx context.Context) (string, error) {
 	// 1. $WSTART_HOST_PATH environment variable.
 	if p := os.Getenv("WSTART_HOST_PATH"); p != "" {
 		return p, nil
 	}
 
-	// 2. Well-known locations via wslpath translation.
-	candidates := helperCandidates()
+	// 2. Location found by a previous run, if the binary is still there.
+	if st := loadHostState(); st != nil {
+		return st.HelperPath, nil
+	}
+
+	// 3. Well-known locations via wslpath translation.
+	candidates := helperCandidates(ctx)
 	for _, c := range candidates {
 		if _, err := os.Stat(c); err == nil {
+			saveHostState(c, nil)
 			return c, nil
 		}
 	}
+	if err := ctx.Err(); err != nil {
+		return "", withKind(ErrInterop, fmt.Errorf("locating wstart-host.exe: %w", err))
+	}
 
 	return "", fmt.Errorf(
-		"wstart-host.exe not found. Run wstart-host.exe --install or set $WSTART_HOST_PATH.\n"+
-			"Searched: %s", strings.Join(candidates, ", "),
+		"%w. Run wstart-host.exe --install or set $WSTART_HOST_PATH.\n"+
+			"Searched: %s", ErrHelperNotFound, strings.Join(candidates, ", "),
 	)
 }
 
-func helperCandidates() []string {
+func helperCandidates(ctx context.Context) []string {
 	var candidates []string
 
 	// 1. Try to resolve %ProgramFiles% via cmd.exe (primary install location).
-	out, err := exec.Command("cmd.exe", "/C", "echo", "%ProgramFiles%").Output()
+	out, err := exec.CommandContext(ctx, "cmd.exe", "/C", "echo", "%ProgramFiles%").Output()
 	if err == nil {
 		winPath := strings.TrimSpace(strings.ReplaceAll(string(out), "\r", ""))
 		if winPath != "" && winPath != "%ProgramFiles%" {
-			wslOut, err := exec.Command("wslpath", "-u", winPath).Output()
+			wslOut, err := exec.CommandContext(ctx, "wslpath", "-u", winPath).Output()
 			if err == nil {
 				programFiles := strings.TrimSpace(string(wslOut))
 				candidates = append(candidates, filepath.Join(programFiles, "wstart", "wstart-host.exe"))
@@ -269,11 +767,11 @@ func helperCandidates() []string {
 	}
 
 	// 2. Try %LOCALAPPDATA% for backwards compatibility with older installs.
-	out, err = exec.Command("cmd.exe", "/C", "echo", "%LOCALAPPDATA%").Output()
+	out, err = exec.CommandContext(ctx, "cmd.exe", "/C", "echo", "%LOCALAPPDATA%").Output()
 	if err == nil {
 		winPath := strings.TrimSpace(strings.ReplaceAll(string(out), "\r", ""))
 		if winPath != "" && winPath != "%LOCALAPPDATA%" {
-			wslOut, err := exec.Command("wslpath", "-u", winPath).Output()
+			wslOut, err := exec.CommandContext(ctx, "wslpath", "-u", winPath).Output()
 			if err == nil {
 				localAppData := strings.TrimSpace(string(wslOut))
 				candidates = append(candidates, filepath.Join(localAppData, "wstart", "wstart-host.exe"))
@@ -290,67 +788,70 @@ func helperCandidates() []string {
 	return candidates
 }
 
-func collectEnvVars(cfg *config.Config) map[string]string {
-	if len(cfg.Env.Forward) == 0 {
-		return nil
+// execWithIO runs the helper in --exec mode with stdio passthrough. It
+// sends req as JSON followed by stdin to helperPath --exec, wiring
+// stdout/stderr to the given writers. Returns the child's exit code.
+func execWithIO(ctx context.Context, helperPath string, req *protocol.LaunchRequest, stdin io.Reader, stdout, stderr io.Writer) (int, error) {
+	reqData, err := json.Marshal(req)
+	if err != nil {
+		return 1, fmt.Errorf("encoding request: %w", err)
 	}
 
-	blocked := make(map[string]bool)
-	for _, b := range cfg.Env.Block {
-		blocked[strings.ToUpper(b)] = true
-	}
+	cmd := exec.CommandContext(ctx, helperPath, "--exec")
+	cmd.Stdin = io.MultiReader(bytes.NewReader(reqData), stdin)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
 
-	vars := make(map[string]string)
-	for _, name := range cfg.Env.Forward {
-		if blocked[strings.ToUpper(name)] {
-			continue
-		}
-		if val, ok := os.LookupEnv(name); ok {
-			vars[name] = val
+	if err := cmd.Run(); err != nil {
+		if ierr := interopFailure(ctx, "helper exec failed", err); ierr != nil {
+			return 1, ierr
 		}
+		return err.(*exec.ExitError).ExitCode(), nil
 	}
-
-	if len(vars) == 0 {
-		return nil
-	}
-	return vars
-}
-
-// invokeHelperExec runs the helper in --exec mode with stdio passthrough.
-// Program output flows directly to the terminal. Returns the child's exit code.
-func invokeHelperExec(helperPath string, req *protocol.LaunchRequest) (int, error) {
-	return execWithIO(helperPath, req, os.Stdin, os.Stdout, os.Stderr)
+	return 0, nil
 }
 
-// execWithIO is the testable core of invokeHelperExec. It sends req as JSON
-// followed by stdin to helperPath --exec, wiring stdout/stderr to the given writers.
-func execWithIO(helperPath string, req *protocol.LaunchRequest, stdin io.Reader, stdout, stderr io.Writer) (int, error) {
+func invokeHelper(ctx context.Context, helperPath string, req *protocol.LaunchRequest) (*protocol.LaunchResponse, error) {
 	reqData, err := json.Marshal(req)
 	if err != nil {
-		return 1, fmt.Errorf("encoding request: %w", err)
+		return nil, fmt.Errorf("encoding request: %w", err)
 	}
 
-	cmd := exec.Command(helperPath, "--exec")
-	cmd.Stdin = io.MultiReader(bytes.NewReader(reqData), stdin)
-	cmd.Stdout = stdout
-	cmd.Stderr = stderr
+	cmd := exec.CommandContext(ctx, helperPath, "--launch")
+	cmd.Stdin = bytes.NewReader(reqData)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
 
 	if err := cmd.Run(); err != nil {
-		if exitErr, ok := err.(*exec.ExitError); ok {
-			return exitErr.ExitCode(), nil
+		if ierr := interopFailure(ctx, "helper failed", err); ierr != nil {
+			return nil, ierr
 		}
-		return 1, fmt.Errorf("helper exec failed: %w", err)
+		stderrStr := strings.TrimSpace(stderr.String())
+		if stderrStr != "" {
+			return nil, fmt.Errorf("helper failed: %s", stderrStr)
+		}
+		return nil, fmt.Errorf("helper failed: %w", err)
 	}
-	return 0, nil
+
+	var resp protocol.LaunchResponse
+	if err := json.Unmarshal(stdout.Bytes(), &resp); err != nil {
+		return nil, fmt.Errorf("decoding response: %w (raw: %s)", err, stdout.String())
+	}
+
+	return &resp, nil
 }
 
-func invokeHelper(helperPath string, req *protocol.LaunchRequest) (*protocol.LaunchResponse, error) {
+// invokeCombined sends a combined request to helperPath --request. Drive
+// and handshake data in the response are written back to the local caches.
+func invokeCombined(ctx context.Context, helperPath string, req *protocol.Request, verbose bool) (*protocol.Response, error) {
 	reqData, err := json.Marshal(req)
 	if err != nil {
 		return nil, fmt.Errorf("encoding request: %w", err)
 	}
 
-	cmd := exec.Command(helperPath, "--launch")
+	cmd := exec.CommandContext(ctx, helperPath, "--request")
 	cmd.Stdin = bytes.NewReader(reqData)
 
 	var stdout, stderr bytes.Buffer
@@ -358,6 +859,9 @@ func invokeHelper(helperPath string, req *protocol.LaunchRequest) (*protocol.Lau
 	cmd.Stderr = &stderr
 
 	if err := cmd.Run(); err != nil {
+		if ierr := interopFailure(ctx, "helper failed", err); ierr != nil {
+			return nil, ierr
+		}
 		stderrStr := strings.TrimSpace(stderr.String())
 		if stderrStr != "" {
 			return nil, fmt.Errorf("helper failed: %s", stderrStr)
@@ -365,10 +869,21 @@ func invokeHelper(helperPath string, req *protocol.LaunchRequest) (*protocol.Lau
 		return nil, fmt.Errorf("helper failed: %w", err)
 	}
 
-	var resp protocol.LaunchResponse
+	var resp protocol.Response
 	if err := json.Unmarshal(stdout.Bytes(), &resp); err != nil {
 		return nil, fmt.Errorf("decoding response: %w (raw: %s)", err, stdout.String())
 	}
 
+	if resp.Hello != nil {
+		saveHostState(helperPath, resp.Hello)
+	}
+	if resp.Drives != nil {
+		if err := drivecache.New(0).Store(resp.Drives); err != nil && verbose {
+			fmt.Fprintf(os.Stderr, "Drive cache: could not write: %v\n", err)
+		}
+	}
+	if resp.Error != "" {
+		return nil, fmt.Errorf("host helper: %s", resp.Error)
+	}
 	return &resp, nil
 }
@@ -0,0 +1,63 @@
+package launch
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/sverrirab/wsl-host-start/internal/protocol"
+)
+
+func TestNegotiateHello(t *testing.T) {
+	t.Setenv("WSTART_TEST_HELPER", "hello")
+
+	hello, err := negotiate(context.Background(), testBinary(t), false)
+	if err != nil {
+		t.Fatalf("negotiate: %v", err)
+	}
+	if hello.ProtocolVersion != 1 || hello.Version != "v9.9.9" {
+		t.Errorf("hello = %+v", hello)
+	}
+	if !hello.Has(protocol.CapDrives) || hello.Has(protocol.CapExec) {
+		t.Errorf("capabilities = %v", hello.Capabilities)
+	}
+}
+
+func TestNegotiateLegacyHelper(t *testing.T) {
+	t.Setenv("WSTART_TEST_HELPER", "legacy")
+
+	hello, err := negotiate(context.Background(), testBinary(t), false)
+	if err != nil {
+		t.Fatalf("negotiate: %v", err)
+	}
+	if hello.ProtocolVersion != 0 {
+		t.Errorf("ProtocolVersion = %d, want 0", hello.ProtocolVersion)
+	}
+	if hello.Version != "v0.1.0" {
+		t.Errorf("Version = %q, want v0.1.0", hello.Version)
+	}
+	if !hello.Has(protocol.CapExec) {
+		t.Error("legacy helper should be assumed to support exec")
+	}
+}
+
+func TestRequireCapabilities(t *testing.T) {
+	hello := &protocol.HelloResponse{
+		Version:      "v1.2.0",
+		Capabilities: []string{protocol.CapLaunch},
+	}
+
+	if err := requireCapabilities(hello, protocol.CapLaunch); err != nil {
+		t.Errorf("launch should be satisfied: %v", err)
+	}
+
+	err := requireCapabilities(hello, protocol.CapLaunch, protocol.CapEnvVars)
+	if err == nil {
+		t.Fatal("expected missing envVars capability")
+	}
+	for _, want := range []string{`"envVars"`, "v1.2.0", "forwarding [env] variables"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q missing %q", err, want)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package launch
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sverrirab/wsl-host-start/internal/pathconv"
+)
+
+// PathOptions mirrors the wslpath flags accepted by wstart path.
+type PathOptions struct {
+	Windows  bool // -w: WSL → Windows path with backslashes
+	Mixed    bool // -m: WSL → Windows path with forward slashes
+	Absolute bool // -a: make relative input absolute first
+}
+
+// RunPath translates each of paths, or each line of r if paths is empty,
+// and prints one result per line (wstart path). Without Windows or Mixed it
+// converts Windows paths to WSL paths, like wslpath -u. Untranslatable
+// paths are reported on stderr; the exit code is that of the first one.
+func RunPath(opts *PathOptions, paths []string, r io.Reader) (*Result, error) {
+	ctx := context.Background()
+	conv, err := newConverter(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{}
+	convert := func(p string) {
+		out, err := translatePath(ctx, conv, opts, p)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "wstart: %s: %v\n", p, err)
+			if result.ExitCode == 0 {
+				result.ExitCode = ExitCode(err)
+			}
+			return
+		}
+		fmt.Println(out)
+	}
+
+	if len(paths) > 0 {
+		for _, p := range paths {
+			convert(p)
+		}
+		return result, nil
+	}
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		if p := strings.TrimSpace(sc.Text()); p != "" {
+			convert(p)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("reading paths: %w", err)
+	}
+	return result, nil
+}
+
+// translatePath converts one path as wslpath would with the same flags,
+// but applies drive aliases in both directions. Relative paths stay
+// relative unless opts.Absolute is set.
+func translatePath(ctx context.Context, conv *pathconv.Converter, opts *PathOptions, p string) (string, error) {
+	if !opts.Windows && !opts.Mixed {
+		if isRelativeWindows(p) {
+			if !opts.Absolute {
+				return strings.ReplaceAll(p, `\`, "/"), nil
+			}
+			cwd, err := os.Getwd()
+			if err != nil {
+				return "", err
+			}
+			return filepath.Join(cwd, strings.ReplaceAll(p, `\`, "/")), nil
+		}
+		return conv.ToWSLContext(ctx, p)
+	}
+
+	var out string
+	if !filepath.IsAbs(p) && !opts.Absolute {
+		out = strings.ReplaceAll(p, "/", `\`)
+	} else {
+		abs, err := filepath.Abs(p)
+		if err != nil {
+			return "", err
+		}
+		out, err = conv.ToWindowsContext(ctx, abs)
+		if err != nil {
+			return "", err
+		}
+	}
+	if opts.Mixed {
+		out = strings.ReplaceAll(out, `\`, "/")
+	}
+	return out, nil
+}
+
+// isRelativeWindows reports whether p is a relative Windows path: no
+// drive letter, no UNC prefix and no leading separator.
+func isRelativeWindows(p string) bool {
+	if strings.HasPrefix(p, `\`) || strings.HasPrefix(p, "/") {
+		return false
+	}
+	return len(p) < 2 || p[1] != ':'
+}
@@ -0,0 +1,53 @@
+package launch
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sverrirab/wsl-host-start/internal/pathconv"
+)
+
+func TestTranslatePathRelative(t *testing.T) {
+	conv := pathconv.NewConverter(nil, nil, true)
+	cwd, _ := os.Getwd()
+
+	tests := []struct {
+		opts PathOptions
+		in   string
+		want string
+	}{
+		{PathOptions{Windows: true}, "src/main.go", `src\main.go`},
+		{PathOptions{Mixed: true}, "src/main.go", "src/main.go"},
+		{PathOptions{}, `src\main.go`, "src/main.go"},
+		{PathOptions{Absolute: true}, `src\main.go`, filepath.Join(cwd, "src/main.go")},
+	}
+	for _, tt := range tests {
+		got, err := translatePath(context.Background(), conv, &tt.opts, tt.in)
+		if err != nil {
+			t.Errorf("translatePath(%+v, %q): %v", tt.opts, tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("translatePath(%+v, %q) = %q, want %q", tt.opts, tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestIsRelativeWindows(t *testing.T) {
+	tests := map[string]bool{
+		`src\a.c`:        true,
+		`a.c`:            true,
+		`C:\src`:         false,
+		`c:`:             false,
+		`\\server\share`: false,
+		`\Windows`:       false,
+		`/mnt/c`:         false,
+	}
+	for in, want := range tests {
+		if got := isRelativeWindows(in); got != want {
+			t.Errorf("isRelativeWindows(%q) = %v, want %v", in, got, want)
+		}
+	}
+}
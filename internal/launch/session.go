@@ -0,0 +1,88 @@
+package launch
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+
+	"github.com/sverrirab/wsl-host-start/internal/protocol"
+	"github.com/sverrirab/wsl-host-start/internal/rpc"
+)
+
+// Session keeps one wstart-host.exe --serve process alive so a batch of
+// requests pays the Windows process spawn cost once.
+type Session struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	client *rpc.Client
+}
+
+// OpenSession starts helperPath --serve. The caller must Close the session.
+// The helper is killed if ctx is done before the session is closed.
+func OpenSession(ctx context.Context, helperPath string) (*Session, error) {
+	cmd := exec.CommandContext(ctx, helperPath, "--serve")
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("opening helper session: %w", err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("opening helper session: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, withKind(ErrInterop, fmt.Errorf("starting helper session: %w", err))
+	}
+	return &Session{
+		cmd:    cmd,
+		stdin:  stdin,
+		client: rpc.NewClient(stdout, stdin),
+	}, nil
+}
+
+// Hello performs the protocol handshake over the session.
+func (s *Session) Hello() (*protocol.HelloResponse, error) {
+	var resp protocol.HelloResponse
+	if err := s.client.Call(protocol.MethodHello, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Drives enumerates the host's drive letters.
+func (s *Session) Drives() (*protocol.DrivesResponse, error) {
+	var resp protocol.DrivesResponse
+	if err := s.client.Call(protocol.MethodDrives, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Launch runs req via ShellExecuteEx on the host.
+func (s *Session) Launch(req *protocol.LaunchRequest) (*protocol.LaunchResponse, error) {
+	var resp protocol.LaunchResponse
+	if err := s.client.Call(protocol.MethodLaunch, req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Check asks whether req would pass the host's policy without launching it.
+func (s *Session) Check(req *protocol.LaunchRequest) (*protocol.CheckResponse, error) {
+	var resp protocol.CheckResponse
+	if err := s.client.Call(protocol.MethodCheck, req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Close ends the session: the helper sees EOF on stdin and exits.
+func (s *Session) Close() error {
+	if err := s.stdin.Close(); err != nil {
+		return err
+	}
+	if err := s.cmd.Wait(); err != nil {
+		return fmt.Errorf("helper session: %w", err)
+	}
+	return nil
+}
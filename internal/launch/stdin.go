@@ -0,0 +1,139 @@
+package launch
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/sverrirab/wsl-host-start/internal/drivecache"
+	"github.com/sverrirab/wsl-host-start/internal/protocol"
+)
+
+// StdinTarget is the target that makes wstart open the content of stdin:
+// it is written to a temporary file on the Windows side, which is opened
+// instead.
+const StdinTarget = "-"
+
+// errStdinTarget rejects StdinTarget where stdin is not free to read or
+// there is more than one target.
+var errStdinTarget = fmt.Errorf("%q (stdin) must be the only target; it cannot be used with -each or -batch", StdinTarget)
+
+// stdinDir is where stdin content is stored, relative to the Windows
+// user's %LOCALAPPDATA%. stdinPrefix marks the files wstart may delete.
+const (
+	stdinDir    = `Temp\wstart`
+	stdinPrefix = "stdin-"
+)
+
+// stdinFile writes stdin to a new file in the Windows user's temp
+// directory and returns its WSL path. Files left there by earlier runs
+// are removed once they are older than the configured max age.
+func (p *pipeline) stdinFile() (string, error) {
+	dir, err := p.stdinTempDir()
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("creating temp directory: %w", err)
+	}
+	removed := cleanStdinFiles(dir, p.cfg.Stdin.MaxAge, time.Now())
+	if p.opts.Verbose && removed > 0 {
+		fmt.Fprintf(os.Stderr, "Removed %d old stdin file(s) from %s\n", removed, dir)
+	}
+
+	path, err := writeStdinFile(dir, p.opts.Ext, p.stdin())
+	if err != nil {
+		return "", err
+	}
+	p.sw.lap("read stdin")
+	if p.opts.Verbose {
+		fmt.Fprintf(os.Stderr, "Stdin: %s\n", path)
+	}
+	return path, nil
+}
+
+// stdinTempDir returns the WSL path of %LOCALAPPDATA%\Temp\wstart, taking
+// %LOCALAPPDATA% from the drive cache or, if that is stale, the helper.
+func (p *pipeline) stdinTempDir() (string, error) {
+	var localAppData string
+	cache := drivecache.New(0)
+	if p.helperPath != "" {
+		if resp, err := cache.Cached(); err == nil {
+			localAppData = resp.LocalAppData
+		}
+	}
+	if localAppData == "" {
+		resp, err := p.send(&protocol.Request{Require: []string{protocol.CapDrives}, Drives: true})
+		if err != nil {
+			return "", fmt.Errorf("locating the Windows temp directory: %w", err)
+		}
+		if resp.Drives == nil || resp.Drives.LocalAppData == "" {
+			return "", errors.New("locating the Windows temp directory: helper did not report %LOCALAPPDATA%")
+		}
+		if p.helperPath != "" {
+			_ = cache.Store(resp.Drives)
+		}
+		localAppData = resp.Drives.LocalAppData
+	}
+	return p.conv.ToWSLContext(p.ctx, strings.TrimRight(localAppData, `\`)+`\`+stdinDir)
+}
+
+// writeStdinFile copies r to a new file in dir with the extension ext
+// (".txt" if empty) and returns the file's path.
+func writeStdinFile(dir, ext string, r io.Reader) (string, error) {
+	if ext == "" {
+		ext = ".txt"
+	}
+	if !strings.HasPrefix(ext, ".") {
+		ext = "." + ext
+	}
+	if strings.ContainsAny(ext, `/\*`) {
+		return "", fmt.Errorf("invalid extension %q", ext)
+	}
+
+	f, err := os.CreateTemp(dir, stdinPrefix+"*"+ext)
+	if err != nil {
+		return "", fmt.Errorf("creating temp file: %w", err)
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return "", fmt.Errorf("reading stdin: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return "", fmt.Errorf("writing temp file: %w", err)
+	}
+	return f.Name(), nil
+}
+
+// cleanStdinFiles removes stdin files in dir last modified more than
+// maxAge before now, and returns how many it removed. A zero maxAge keeps
+// every file.
+func cleanStdinFiles(dir string, maxAge time.Duration, now time.Time) int {
+	if maxAge <= 0 {
+		return 0
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return 0
+	}
+	removed := 0
+	for _, e := range entries {
+		if !e.Type().IsRegular() || !strings.HasPrefix(e.Name(), stdinPrefix) {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil || now.Sub(info.ModTime()) <= maxAge {
+			continue
+		}
+		if os.Remove(filepath.Join(dir, e.Name())) == nil {
+			removed++
+		}
+	}
+	return removed
+}
@@ -0,0 +1,147 @@
+package launch
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sverrirab/wsl-host-start/internal/protocol"
+)
+
+func TestWriteStdinFile(t *testing.T) {
+	dir := t.TempDir()
+
+	tests := []struct {
+		ext     string
+		wantExt string
+	}{
+		{"", ".txt"},
+		{".html", ".html"},
+		{"md", ".md"},
+	}
+	for _, tt := range tests {
+		path, err := writeStdinFile(dir, tt.ext, strings.NewReader("<p>report</p>"))
+		if err != nil {
+			t.Fatalf("writeStdinFile(%q): %v", tt.ext, err)
+		}
+		if filepath.Dir(path) != dir || !strings.HasPrefix(filepath.Base(path), stdinPrefix) || filepath.Ext(path) != tt.wantExt {
+			t.Errorf("writeStdinFile(%q) = %q, want %s*%s in %s", tt.ext, path, stdinPrefix, tt.wantExt, dir)
+		}
+		if data, _ := os.ReadFile(path); string(data) != "<p>report</p>" {
+			t.Errorf("file content = %q", data)
+		}
+	}
+
+	if _, err := writeStdinFile(dir, "../x", strings.NewReader("")); err == nil {
+		t.Error("extension with a path separator: expected an error")
+	}
+}
+
+func TestCleanStdinFiles(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	files := map[string]time.Duration{
+		"stdin-old.html":  48 * time.Hour,
+		"stdin-new.html":  time.Hour,
+		"other-old.html":  48 * time.Hour,
+		"stdin-edge.html": 24*time.Hour - time.Minute,
+	}
+	for name, age := range files {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, now.Add(-age), now.Add(-age)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if n := cleanStdinFiles(dir, 0, now); n != 0 {
+		t.Errorf("max age 0 removed %d files, want none", n)
+	}
+	if n := cleanStdinFiles(dir, 24*time.Hour, now); n != 1 {
+		t.Errorf("removed %d files, want 1", n)
+	}
+	for name := range files {
+		_, err := os.Stat(filepath.Join(dir, name))
+		if gone := err != nil; gone != (name == "stdin-old.html") {
+			t.Errorf("%s: removed=%v", name, gone)
+		}
+	}
+}
+
+// stdinTransport is a helper that reports %LOCALAPPDATA% and records how
+// targets are launched.
+type stdinTransport struct {
+	localAppData string
+	launches     []*protocol.LaunchRequest
+	execs        int
+}
+
+func (f *stdinTransport) Request(ctx context.Context, req *protocol.Request) (*protocol.Response, error) {
+	resp := &protocol.Response{}
+	if req.Hello {
+		resp.Hello = &protocol.HelloResponse{ProtocolVersion: protocol.ProtocolVersion, Capabilities: protocol.Capabilities()}
+	}
+	if req.Drives {
+		resp.Drives = &protocol.DrivesResponse{LocalAppData: f.localAppData}
+	}
+	if req.Launch != nil {
+		f.launches = append(f.launches, req.Launch)
+		resp.Launch = &protocol.LaunchResponse{PID: 1}
+	}
+	return resp, nil
+}
+
+func (f *stdinTransport) Exec(ctx context.Context, req *protocol.LaunchRequest, stdin io.Reader, stdout, stderr io.Writer) (int, error) {
+	f.execs++
+	return 0, nil
+}
+
+// TestRunWaitStdin checks that "wstart -wait -" opens the stdin document
+// with ShellExecuteEx: --exec runs programs and cannot open a document.
+func TestRunWaitStdin(t *testing.T) {
+	fakeWslpath(t)
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("WSL_DISTRO_NAME", "Test")
+	localAppData := t.TempDir()
+	fake := &stdinTransport{localAppData: `\\wsl.localhost\Test` + strings.ReplaceAll(localAppData, "/", `\`)}
+
+	res, err := RunContext(context.Background(), &Options{
+		Target:    StdinTarget,
+		Wait:      true,
+		Ext:       ".html",
+		Stdin:     strings.NewReader("<p>report</p>"),
+		Transport: fake,
+	})
+	if err != nil {
+		t.Fatalf("RunContext: %v", err)
+	}
+	if fake.execs != 0 || len(fake.launches) != 1 {
+		t.Fatalf("got %d exec and %d launch requests, want one launch", fake.execs, len(fake.launches))
+	}
+	if l := fake.launches[0]; !l.Wait || !strings.HasSuffix(l.File, ".html") {
+		t.Errorf("launch request = %+v, want a waited launch of the .html file", l)
+	}
+	if res.PID != 1 {
+		t.Errorf("PID = %d, want 1", res.PID)
+	}
+	files, _ := filepath.Glob(filepath.Join(localAppData, "Temp", "wstart", stdinPrefix+"*"))
+	if len(files) != 0 {
+		t.Errorf("stdin files left after -wait: %v", files)
+	}
+}
+
+func TestStdinTargetRejectedForManyTargets(t *testing.T) {
+	if _, err := RunEach(&Options{}, []string{"a.txt", StdinTarget}); !errors.Is(err, errStdinTarget) {
+		t.Errorf("RunEach with -: err = %v, want errStdinTarget", err)
+	}
+	if ExitCode(errStdinTarget) != protocol.ExitInternal {
+		t.Errorf("ExitCode = %d, want %d", ExitCode(errStdinTarget), protocol.ExitInternal)
+	}
+}
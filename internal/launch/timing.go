@@ -0,0 +1,38 @@
+package launch
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// stopwatch prints per-phase latency to stderr when -timing is given, so
+// the cost of each Windows process spawn across interop can be measured.
+type stopwatch struct {
+	enabled bool
+	start   time.Time
+	last    time.Time
+}
+
+func newStopwatch(enabled bool) *stopwatch {
+	now := time.Now()
+	return &stopwatch{enabled: enabled, start: now, last: now}
+}
+
+// lap reports the time since the previous lap under the given phase name.
+func (s *stopwatch) lap(phase string) {
+	if !s.enabled {
+		return
+	}
+	now := time.Now()
+	fmt.Fprintf(os.Stderr, "timing: %-18s %8.1f ms\n", phase, float64(now.Sub(s.last).Microseconds())/1000)
+	s.last = now
+}
+
+// total reports the time since the stopwatch was created.
+func (s *stopwatch) total() {
+	if !s.enabled {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "timing: %-18s %8.1f ms\n", "total", float64(time.Since(s.start).Microseconds())/1000)
+}
@@ -0,0 +1,92 @@
+package launch
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/sverrirab/wsl-host-start/internal/drivecache"
+	"github.com/sverrirab/wsl-host-start/internal/protocol"
+)
+
+// Transport carries requests to the Windows helper. By default the
+// pipeline spawns wstart-host.exe (see HelperTransport); embedders and
+// tests can set Options.Transport to substitute their own.
+type Transport interface {
+	// Request sends a combined request and returns the combined response.
+	// A request with only Hello set performs the protocol handshake.
+	Request(ctx context.Context, req *protocol.Request) (*protocol.Response, error)
+
+	// Exec runs req with stdio passthrough and returns the child's exit code.
+	Exec(ctx context.Context, req *protocol.LaunchRequest, stdin io.Reader, stdout, stderr io.Writer) (int, error)
+}
+
+// HelperTransport returns a Transport that spawns the helper at helperPath
+// for every request.
+func HelperTransport(helperPath string) Transport {
+	return &helperTransport{path: helperPath}
+}
+
+type helperTransport struct {
+	path    string
+	hello   *protocol.HelloResponse // nil until negotiated
+	verbose bool
+}
+
+// Request uses --request when the helper supports it. Older helpers get
+// one call per part of the request: --hello, --drives and --launch.
+func (t *helperTransport) Request(ctx context.Context, req *protocol.Request) (*protocol.Response, error) {
+	if t.hello == nil {
+		hello, err := negotiate(ctx, t.path, t.verbose)
+		if err != nil {
+			return nil, err
+		}
+		t.hello = hello
+	}
+	if t.hello.Has(protocol.CapRequest) && (req.Drives || req.Launch != nil || len(req.Launches) > 0) {
+		return invokeCombined(ctx, t.path, req, t.verbose)
+	}
+
+	if err := requireCapabilities(t.hello, req.Require...); err != nil {
+		return nil, err
+	}
+	if len(req.Launches) > 0 {
+		return nil, requireCapabilities(t.hello, protocol.CapMulti)
+	}
+	resp := &protocol.Response{}
+	if req.Hello {
+		resp.Hello = t.hello
+	}
+	if req.Drives {
+		drives, err := drivecache.New(0).RefreshContext(ctx, t.path)
+		if err != nil {
+			return nil, withKind(ErrInterop, err)
+		}
+		resp.Drives = drives
+	}
+	if req.Launch != nil {
+		launch, err := invokeHelper(ctx, t.path, req.Launch)
+		if err != nil {
+			return nil, err
+		}
+		resp.Launch = launch
+	}
+	return resp, nil
+}
+
+func (t *helperTransport) Exec(ctx context.Context, req *protocol.LaunchRequest, stdin io.Reader, stdout, stderr io.Writer) (int, error) {
+	return execWithIO(ctx, t.path, req, stdin, stdout, stderr)
+}
+
+// send passes req to the pipeline's transport and turns a top-level error
+// in the response into a Go error.
+func (p *pipeline) send(req *protocol.Request) (*protocol.Response, error) {
+	resp, err := p.transport.Request(p.ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Error != "" {
+		return nil, fmt.Errorf("host helper: %s", resp.Error)
+	}
+	return resp, nil
+}
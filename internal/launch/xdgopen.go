@@ -0,0 +1,111 @@
+package launch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/sverrirab/wsl-host-start/internal/pathconv"
+	"github.com/sverrirab/wsl-host-start/internal/protocol"
+)
+
+// Exit codes documented by xdg-open(1).
+const (
+	XdgExitOK       = 0 // success
+	XdgExitUsage    = 1 // error in command line syntax
+	XdgExitNotFound = 2 // the file does not exist
+	XdgExitNoTool   = 3 // a required tool could not be found
+	XdgExitFailed   = 4 // the action failed
+)
+
+// XdgOpenNames are the program names under which wstart behaves like
+// xdg-open (see RunXdgOpen).
+var XdgOpenNames = []string{"xdg-open", "wslview", "sensible-browser"}
+
+// XdgOpenArgs returns the single URI or path of an xdg-open style command
+// line. Literal "%s" arguments, left behind by callers that append the URL
+// to a BROWSER value instead of substituting it, are dropped.
+func XdgOpenArgs(args []string) (string, error) {
+	var targets []string
+	for _, a := range args {
+		if a == "%s" {
+			continue
+		}
+		if strings.HasPrefix(a, "-") {
+			return "", fmt.Errorf("unexpected option '%s'", a)
+		}
+		targets = append(targets, a)
+	}
+	switch len(targets) {
+	case 0:
+		return "", errors.New("no file or URL given")
+	case 1:
+		return targets[0], nil
+	default:
+		return "", errors.New("unexpected argument: only one file or URL can be opened")
+	}
+}
+
+// RunXdgOpen opens target like Run but with xdg-open semantics: it never
+// waits for the program, and a local file that does not exist is an error
+// before the helper is asked. Use XdgExitCode to map the error.
+func RunXdgOpen(opts *Options, target string) error {
+	if err := checkOpenTarget(target); err != nil {
+		return err
+	}
+	o := *opts
+	o.Target = target
+	o.Args = nil
+	o.Wait = false
+	_, err := RunContext(context.Background(), &o)
+	return err
+}
+
+// checkOpenTarget reports a local path or file:// URI that does not exist.
+// Other URIs are left for their Windows handler.
+func checkOpenTarget(target string) error {
+	path := target
+	switch pathconv.URIScheme(target) {
+	case "":
+	case "file":
+		u, err := url.Parse(target)
+		if err != nil || (u.Host != "" && !strings.EqualFold(u.Host, "localhost")) || !strings.HasPrefix(u.Path, "/") {
+			return nil
+		}
+		path = u.Path
+	default:
+		if _, err := os.Lstat(target); err != nil {
+			return nil
+		}
+	}
+	if _, err := os.Stat(path); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			err = fmt.Errorf("file '%s' does not exist", path)
+		}
+		return withExit(protocol.ExitNotFound, err)
+	}
+	return nil
+}
+
+// XdgExitCode maps an error from RunXdgOpen to the exit code xdg-open
+// would use for it.
+func XdgExitCode(err error) int {
+	switch {
+	case err == nil:
+		return XdgExitOK
+	case errors.Is(err, ErrHelperNotFound), errors.Is(err, ErrInterop), errors.Is(err, ErrVersionMismatch):
+		return XdgExitNoTool
+	case ExitCode(err) == protocol.ExitNotFound:
+		var he *HelperError
+		if errors.As(err, &he) && he.Category == protocol.ErrNoAssociation {
+			return XdgExitFailed
+		}
+		return XdgExitNotFound
+	default:
+		return XdgExitFailed
+	}
+}
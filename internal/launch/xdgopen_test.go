@@ -0,0 +1,88 @@
+package launch
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sverrirab/wsl-host-start/internal/protocol"
+)
+
+func TestXdgOpenArgs(t *testing.T) {
+	tests := []struct {
+		args    []string
+		want    string
+		wantErr bool
+	}{
+		{[]string{"https://example.com"}, "https://example.com", false},
+		{[]string{"%s", "https://example.com"}, "https://example.com", false},
+		{[]string{"report.pdf", "%s"}, "report.pdf", false},
+		{nil, "", true},
+		{[]string{"%s"}, "", true},
+		{[]string{"a", "b"}, "", true},
+		{[]string{"--bogus", "a"}, "", true},
+	}
+	for _, tt := range tests {
+		got, err := XdgOpenArgs(tt.args)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("XdgOpenArgs(%q) = %q, %v; want %q, error %v", tt.args, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestCheckOpenTarget(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "a.html")
+	if err := os.WriteFile(file, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(dir)
+
+	tests := []struct {
+		target  string
+		missing bool
+	}{
+		{"a.html", false},
+		{file, false},
+		{"file://" + file + "#top", false},
+		{"missing.html", true},
+		{dir + "/missing.html", true},
+		{"file://" + dir + "/missing.html", true},
+		{"file://server/share/missing.html", false},
+		{"https://example.com/missing.html", false},
+		{"mailto:a@b.com", false},
+	}
+	for _, tt := range tests {
+		err := checkOpenTarget(tt.target)
+		if missing := err != nil; missing != tt.missing {
+			t.Errorf("checkOpenTarget(%q) = %v, want missing=%v", tt.target, err, tt.missing)
+		}
+		if err != nil && XdgExitCode(err) != XdgExitNotFound {
+			t.Errorf("checkOpenTarget(%q): XdgExitCode = %d, want %d", tt.target, XdgExitCode(err), XdgExitNotFound)
+		}
+	}
+}
+
+func TestXdgExitCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"success", nil, XdgExitOK},
+		{"no helper", fmt.Errorf("%w. Run wstart-host.exe --install", ErrHelperNotFound), XdgExitNoTool},
+		{"interop", withKind(ErrInterop, errors.New("exec format error")), XdgExitNoTool},
+		{"old helper", withKind(ErrVersionMismatch, errors.New("too old")), XdgExitNoTool},
+		{"file not found", &HelperError{Category: protocol.ErrFileNotFound}, XdgExitNotFound},
+		{"no association", &HelperError{Category: protocol.ErrNoAssociation}, XdgExitFailed},
+		{"denied", &HelperError{Category: protocol.ErrDeniedAllowlist}, XdgExitFailed},
+		{"other", errors.New("boom"), XdgExitFailed},
+	}
+	for _, tt := range tests {
+		if got := XdgExitCode(tt.err); got != tt.want {
+			t.Errorf("%s: XdgExitCode = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,230 @@
+package pathconv
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// mountinfoPath and wslConfPath are read once per process to translate
+// paths on Windows drives without forking wslpath.
+const (
+	mountinfoPath = "/proc/self/mountinfo"
+	wslConfPath   = "/etc/wsl.conf"
+
+	defaultAutomountRoot = "/mnt/"
+)
+
+// mount is one entry of the mount table. winRoot is the Windows path
+// mounted at point, or "" for mounts that are not Windows drives.
+type mount struct {
+	point   string
+	winRoot string
+}
+
+// mountTable translates WSL paths under drvfs/9p mounts to Windows paths.
+type mountTable struct {
+	mounts []mount
+
+	// automountRoot is used only when no Windows mounts could be read:
+	// <root><letter>/... is then assumed to be drive <letter>.
+	automountRoot string
+}
+
+// systemMounts returns the mount table of the running system.
+var systemMounts = sync.OnceValue(func() *mountTable {
+	t := &mountTable{automountRoot: defaultAutomountRoot}
+	if f, err := os.Open(mountinfoPath); err == nil {
+		t.mounts, _ = parseMountinfo(f)
+		f.Close()
+	}
+	if f, err := os.Open(wslConfPath); err == nil {
+		if root := parseAutomountRoot(f); root != "" {
+			t.automountRoot = root
+		}
+		f.Close()
+	}
+	return t
+})
+
+// parseMountinfo reads the mount table in the format of
+// /proc/self/mountinfo (see proc(5)):
+//
+//	36 25 0:58 / /mnt/c rw,noatime - 9p C:\134 rw,aname=drvfs;path=C:\;uid=1000
+func parseMountinfo(r io.Reader) ([]mount, error) {
+	var mounts []mount
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		sep := -1
+		for i, f := range fields {
+			if f == "-" {
+				sep = i
+				break
+			}
+		}
+		if sep < 5 || len(fields) < sep+3 {
+			continue
+		}
+		root := unescapeMountField(fields[3])
+		m := mount{point: unescapeMountField(fields[4])}
+		fsType := fields[sep+1]
+		source := unescapeMountField(fields[sep+2])
+		var superOpts string
+		if len(fields) > sep+3 {
+			superOpts = unescapeMountField(fields[sep+3])
+		}
+		if winRoot := windowsRoot(fsType, source, superOpts); winRoot != "" {
+			if root != "/" {
+				winRoot = strings.TrimRight(winRoot, `\`) + strings.ReplaceAll(root, "/", `\`)
+			}
+			m.winRoot = winRoot
+		}
+		mounts = append(mounts, m)
+	}
+	return mounts, sc.Err()
+}
+
+// windowsRoot returns the Windows path behind a drvfs mount, or "" if the
+// mount is not one. WSL1 mounts drvfs directly; WSL2 mounts it over 9p or
+// virtiofs with the Windows path in the path= option.
+func windowsRoot(fsType, source, superOpts string) string {
+	switch fsType {
+	case "drvfs", "9p", "virtiofs":
+	default:
+		return ""
+	}
+	for _, opt := range strings.FieldsFunc(superOpts, func(r rune) bool { return r == ',' || r == ';' }) {
+		if v, ok := strings.CutPrefix(opt, "path="); ok && isWindowsRoot(v) {
+			return driveRoot(v)
+		}
+	}
+	if fsType == "drvfs" && isWindowsRoot(source) {
+		return driveRoot(source)
+	}
+	return ""
+}
+
+// driveRoot spells a bare drive "C:" as "C:", as wslpath does.
+func driveRoot(s string) string {
+	if len(s) == 2 && s[1] == ':' {
+		return s + `\`
+	}
+	return s
+}
+
+// isWindowsRoot reports whether s is a drive (C:, C:\...) or UNC path.
+func isWindowsRoot(s string) bool {
+	if strings.HasPrefix(s, `\\`) {
+		return true
+	}
+	return len(s) >= 2 && s[1] == ':' && isLetter(s[0])
+}
+
+func isLetter(b byte) bool {
+	return (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z')
+}
+
+// unescapeMountField decodes the octal escapes (\040 for space, \134 for
+// backslash, ...) the kernel uses in mountinfo fields.
+func unescapeMountField(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+4 <= len(s) {
+			if n, err := strconv.ParseUint(s[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(n))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// parseAutomountRoot returns the root= setting of the [automount] section
+// of a wsl.conf file, with a trailing slash, or "" if it is not set.
+func parseAutomountRoot(r io.Reader) string {
+	section := ""
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.ToLower(strings.TrimSpace(line[1 : len(line)-1]))
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok || section != "automount" || strings.TrimSpace(strings.ToLower(key)) != "root" {
+			continue
+		}
+		if i := strings.IndexAny(value, "#;"); i >= 0 {
+			value = value[:i]
+		}
+		value = strings.Trim(strings.TrimSpace(value), `"'`)
+		if value == "" {
+			return ""
+		}
+		return strings.TrimRight(value, "/") + "/"
+	}
+	return ""
+}
+
+// translate converts an absolute, clean WSL path to a Windows path. It
+// reports false when the path is not on a Windows drive, in which case the
+// caller falls back to wslpath.
+func (t *mountTable) translate(wslPath string) (string, bool) {
+	best := -1
+	for i, m := range t.mounts {
+		if wslPath != m.point && !strings.HasPrefix(wslPath, strings.TrimRight(m.point, "/")+"/") {
+			continue
+		}
+		// Later entries are mounted on top of earlier ones.
+		if best < 0 || len(m.point) >= len(t.mounts[best].point) {
+			best = i
+		}
+	}
+	if best >= 0 {
+		m := t.mounts[best]
+		if m.winRoot == "" {
+			return "", false
+		}
+		return joinWindows(m.winRoot, strings.TrimPrefix(wslPath, m.point)), true
+	}
+
+	if t.hasWindowsMounts() || t.automountRoot == "" {
+		return "", false
+	}
+	rest, ok := strings.CutPrefix(wslPath, t.automountRoot)
+	if !ok || rest == "" || !isLetter(rest[0]) || (len(rest) > 1 && rest[1] != '/') {
+		return "", false
+	}
+	return joinWindows(strings.ToUpper(rest[:1])+`:\`, rest[1:]), true
+}
+
+func (t *mountTable) hasWindowsMounts() bool {
+	for _, m := range t.mounts {
+		if m.winRoot != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// joinWindows appends the slash-separated rest to a Windows root the way
+// wslpath does: the root itself is returned unchanged ("C:\").
+func joinWindows(winRoot, rest string) string {
+	rest = strings.Trim(rest, "/")
+	if rest == "" {
+		return winRoot
+	}
+	return strings.TrimRight(winRoot, `\`) + `\` + strings.ReplaceAll(rest, "/", `\`)
+}
@@ -0,0 +1,137 @@
+package pathconv
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+// wsl2Mountinfo is a trimmed /proc/self/mountinfo from WSL2 with the
+// default automount, a custom drvfs mount with a space in its name, a UNC
+// share, a bind mount of a subdirectory and a tmpfs on top of a drive.
+const wsl2Mountinfo = `61 66 8:48 / / rw,relatime - ext4 /dev/sdd rw,discard,errors=remount-ro
+81 61 0:53 / /mnt/c rw,noatime - 9p C:\134 rw,dirsync,aname=drvfs;path=C:\134;uid=1000;gid=1000;symlinkroot=/mnt/,mmap,access=client,msize=65536,trans=fd,rfd=5,wfd=5
+82 61 0:54 / /mnt/d rw,noatime - 9p D:\134 rw,dirsync,aname=drvfs;path=D:\134;uid=1000;gid=1000;symlinkroot=/mnt/,mmap,access=client,msize=65536,trans=fd,rfd=7,wfd=7
+90 61 0:60 / /data/my\040assets rw,noatime - 9p D:\134assets rw,dirsync,aname=drvfs;path=D:\134assets;uid=1000;gid=1000
+91 61 0:61 / /mnt/share rw,noatime - 9p \134\134server\134share rw,dirsync,aname=drvfs;path=\134\134server\134share;uid=1000
+92 61 0:53 /Users/me /home/me/win rw,noatime - 9p C:\134 rw,dirsync,aname=drvfs;path=C:\134;uid=1000
+93 81 0:70 / /mnt/c/tmp rw,relatime - tmpfs none rw
+70 61 0:40 / /usr/lib/wsl/drivers ro,nosuid,nodev,noatime - 9p drivers ro,dirsync,aname=drivers;fmask=222;dmask=222,mmap,access=client,msize=65536,trans=fd,rfd=8,wfd=8
+`
+
+// wsl1Mountinfo mounts drvfs directly, with the source as the drive.
+const wsl1Mountinfo = `2 0 0:2 / / rw,noatime - lxfs rootfs rw
+7 2 0:4 / /mnt/c rw,noatime - drvfs C:\134 rw,noatime,uid=1000,gid=1000,case=off
+8 2 0:5 / /mnt/e rw,noatime - drvfs E: rw,noatime,uid=1000,gid=1000
+`
+
+func TestMountTableTranslate(t *testing.T) {
+	parse := func(s string) *mountTable {
+		mounts, err := parseMountinfo(strings.NewReader(s))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &mountTable{mounts: mounts, automountRoot: defaultAutomountRoot}
+	}
+	wsl2, wsl1 := parse(wsl2Mountinfo), parse(wsl1Mountinfo)
+
+	tests := []struct {
+		name   string
+		table  *mountTable
+		input  string
+		want   string
+		wantOK bool
+	}{
+		{"drive root", wsl2, "/mnt/c", `C:\`, true},
+		{"file on drive", wsl2, "/mnt/c/Users/me/report.pdf", `C:\Users\me\report.pdf`, true},
+		{"second drive", wsl2, "/mnt/d/src", `D:\src`, true},
+		{"custom mount with space", wsl2, "/data/my assets/logo.png", `D:\assets\logo.png`, true},
+		{"custom mount root", wsl2, "/data/my assets", `D:\assets`, true},
+		{"UNC share", wsl2, "/mnt/share/docs/a.txt", `\\server\share\docs\a.txt`, true},
+		{"bind mount of subdirectory", wsl2, "/home/me/win/Desktop", `C:\Users\me\Desktop`, true},
+		{"prefix is not a mount", wsl2, "/mnt/cc/x", "", false},
+		{"Linux filesystem", wsl2, "/home/me/project", "", false},
+		{"tmpfs over drive", wsl2, "/mnt/c/tmp/x", "", false},
+		{"9p mount that is not drvfs", wsl2, "/usr/lib/wsl/drivers/x", "", false},
+		{"WSL1 drive", wsl1, "/mnt/c/Windows", `C:\Windows`, true},
+		{"WSL1 bare drive source", wsl1, "/mnt/e", `E:\`, true},
+		{"WSL1 lxfs", wsl1, "/home/me", "", false},
+		{"automount fallback", &mountTable{automountRoot: "/win/"}, "/win/c/Users", `C:\Users`, true},
+		{"automount fallback drive root", &mountTable{automountRoot: "/win/"}, "/win/d", `D:\`, true},
+		{"automount fallback not a drive", &mountTable{automountRoot: "/win/"}, "/win/cd/x", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := tt.table.translate(tt.input)
+		if ok != tt.wantOK || got != tt.want {
+			t.Errorf("%s: translate(%q) = %q, %v; want %q, %v", tt.name, tt.input, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestUnescapeMountField(t *testing.T) {
+	tests := map[string]string{
+		`C:\134`:                  `C:\`,
+		`/data/my\040assets`:      "/data/my assets",
+		`\134\134server\134share`: `\\server\share`,
+		`plain`:                   "plain",
+		`trailing\13`:             `trailing\13`,
+	}
+	for in, want := range tests {
+		if got := unescapeMountField(in); got != want {
+			t.Errorf("unescapeMountField(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestParseAutomountRoot(t *testing.T) {
+	tests := []struct {
+		conf string
+		want string
+	}{
+		{"[automount]\nroot = /win\n", "/win/"},
+		{"[automount]\nenabled = true\nroot = \"/windir/\" # custom\n", "/windir/"},
+		{"[network]\nroot = /nope\n", ""},
+		{"# no automount section\n", ""},
+		{"[Automount]\nRoot=/\n", "/"},
+	}
+	for _, tt := range tests {
+		if got := parseAutomountRoot(strings.NewReader(tt.conf)); got != tt.want {
+			t.Errorf("parseAutomountRoot(%q) = %q, want %q", tt.conf, got, tt.want)
+		}
+	}
+}
+
+func TestTranslateMountedSkipsUncleanPaths(t *testing.T) {
+	mounts, _ := parseMountinfo(strings.NewReader(wsl2Mountinfo))
+	c := &Converter{mounts: &mountTable{mounts: mounts}}
+	for _, p := range []string{"/mnt/c/Users/", "/mnt/c/Users/../x", "/mnt/c//x"} {
+		if got, ok := c.translateMounted(p); ok {
+			t.Errorf("translateMounted(%q) = %q, want fallback to wslpath", p, got)
+		}
+	}
+}
+
+// TestMountTableMatchesWslpath compares the native translation with
+// wslpath for every Windows mount on this system. It only runs in WSL.
+func TestMountTableMatchesWslpath(t *testing.T) {
+	if _, err := exec.LookPath("wslpath"); err != nil {
+		t.Skip("wslpath not available (not running in WSL)")
+	}
+	table := systemMounts()
+	for _, m := range table.mounts {
+		if m.winRoot == "" {
+			continue
+		}
+		got, ok := table.translate(m.point)
+		if !ok {
+			continue // shadowed by a later mount
+		}
+		out, err := exec.Command("wslpath", "-w", m.point).Output()
+		if err != nil {
+			continue
+		}
+		if want := strings.TrimSpace(string(out)); got != want {
+			t.Errorf("translate(%q) = %q, wslpath says %q", m.point, got, want)
+		}
+	}
+}
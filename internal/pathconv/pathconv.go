@@ -3,6 +3,8 @@
 package pathconv
 
 import (
+	"context"
+	"os"
 	"path/filepath"
 	"strings"
 
@@ -19,6 +21,13 @@ type Alias struct {
 type Converter struct {
 	aliases       []Alias
 	preferAliases bool
+
+	// networks maps network drive letters to their UNC targets. They are
+	// only expanded by ToWSL, never applied.
+	networks []Alias
+
+	// mounts translates paths on Windows drives; nil means systemMounts.
+	mounts *mountTable
 }
 
 // NewConverter creates a path converter with the given drive aliases.
@@ -27,11 +36,17 @@ func NewConverter(drives []protocol.DriveInfo, configAliases map[string]string,
 
 	// Build alias list from auto-detected drives (subst drives only).
 	for _, d := range drives {
-		if d.Type == protocol.DriveSubst && d.Target != "" {
+		if d.Target == "" {
+			continue
+		}
+		switch d.Type {
+		case protocol.DriveSubst:
 			c.aliases = append(c.aliases, Alias{
 				Letter: d.Letter,
 				Target: d.Target,
 			})
+		case protocol.DriveNetwork:
+			c.networks = append(c.networks, Alias{Letter: d.Letter, Target: d.Target})
 		}
 	}
 
@@ -55,7 +70,7 @@ func NewConverter(drives []protocol.DriveInfo, configAliases map[string]string,
 }
 
 // IsBareCommand returns true if the input looks like a bare command name
-// (e.g. "p4", "notepad.exe") rather than a path or URL. Bare commands
+// (e.g. "p4", "notepad.exe") rather than a path or URI. Bare commands
 // contain no path separators and don't start with "." — they should be
 // passed through to Windows for PATH + PATHEXT resolution.
 func IsBareCommand(s string) bool {
@@ -65,20 +80,27 @@ func IsBareCommand(s string) bool {
 	if strings.HasPrefix(s, ".") {
 		return false
 	}
-	lower := strings.ToLower(s)
-	if strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://") {
-		return false
-	}
-	return true
+	return URIScheme(s) == ""
 }
 
 // ToWindows converts a WSL path to a Windows path.
 // It resolves relative paths, calls wslpath, and applies alias mapping.
-// URLs (http://, https://) and bare command names are returned unchanged.
+// URIs (https:, mailto:, ms-settings:, ...) and bare command names are
+// returned unchanged, except that file:// URIs are converted to Windows
+// paths.
 func (c *Converter) ToWindows(wslPath string) (string, error) {
-	// Pass URLs through unchanged.
-	lower := strings.ToLower(wslPath)
-	if strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://") {
+	return c.ToWindowsContext(context.Background(), wslPath)
+}
+
+// ToWindowsContext is like ToWindows but kills the wslpath call if ctx is
+// done first.
+func (c *Converter) ToWindowsContext(ctx context.Context, wslPath string) (string, error) {
+	// Pass URIs through unchanged; the Windows handler of the scheme
+	// opens them.
+	if isURI(wslPath) {
+		if URIScheme(wslPath) == "file" {
+			return c.fromFileURI(ctx, wslPath)
+		}
 		return wslPath, nil
 	}
 
@@ -97,24 +119,42 @@ func (c *Converter) ToWindows(wslPath string) (string, error) {
 		wslPath = abs
 	}
 
-	// Translate using wslpath.
-	winPath, err := runWslpath(wslPath)
-	if err != nil {
-		return "", err
+	// Translate paths on Windows drives from the mount table, and
+	// everything else (e.g. \\wsl.localhost\ paths) using wslpath.
+	winPath, ok := c.translateMounted(wslPath)
+	if !ok {
+		var err error
+		winPath, err = runWslpath(ctx, "-w", wslPath)
+		if err != nil {
+			return "", err
+		}
 	}
 
 	// Apply alias mapping if enabled.
 	if c.preferAliases {
-		winPath = c.applyAlias(winPath)
+		winPath = c.ApplyAlias(winPath)
 	}
 
 	return winPath, nil
 }
 
-// applyAlias performs longest-prefix matching to replace a physical path
+// translateMounted translates wslPath using the mount table. Only clean
+// paths are handled, so trailing slashes and ".." keep wslpath's semantics.
+func (c *Converter) translateMounted(wslPath string) (string, bool) {
+	if filepath.Clean(wslPath) != wslPath {
+		return "", false
+	}
+	mounts := c.mounts
+	if mounts == nil {
+		mounts = systemMounts()
+	}
+	return mounts.translate(wslPath)
+}
+
+// ApplyAlias performs longest-prefix matching to replace a physical path
 // with an aliased drive letter.
 // Example: C:\dev\workspace\project with alias P:→C:\dev\workspace becomes P:\project
-func (c *Converter) applyAlias(winPath string) string {
+func (c *Converter) ApplyAlias(winPath string) string {
 	bestLen := 0
 	bestLetter := ""
 	bestTarget := ""
@@ -150,3 +190,50 @@ func (c *Converter) applyAlias(winPath string) string {
 	suffix := strings.ReplaceAll(winPath[len(bestTarget):], "/", `\`)
 	return strings.ToUpper(bestLetter) + ":" + suffix
 }
+
+// IsPathArg reports whether a program argument refers to an existing WSL
+// path that a Windows program could not open as given: it contains a slash,
+// is not a URI, and exists. Bare names like "foo.c" are left alone since
+// they resolve against the translated working directory.
+func IsPathArg(arg string) bool {
+	if !strings.Contains(arg, "/") || URIScheme(arg) != "" {
+		return false
+	}
+	_, err := os.Stat(arg)
+	return err == nil
+}
+
+// TranslateArg converts a program argument that refers to a WSL path to a
+// Windows path. For "--flag=value" and "-flag=value" arguments only the
+// value is considered. file:// URIs are converted like targets. Unless
+// force is set, other arguments for which IsPathArg is false are returned
+// unchanged.
+func (c *Converter) TranslateArg(ctx context.Context, arg string, force bool) (string, error) {
+	prefix, value := "", arg
+	if strings.HasPrefix(arg, "-") {
+		flag, v, ok := strings.Cut(arg, "=")
+		if !ok {
+			return arg, nil
+		}
+		prefix, value = flag+"=", v
+	}
+	if URIScheme(value) == "file" {
+		winPath, err := c.fromFileURI(ctx, value)
+		if err != nil {
+			return "", err
+		}
+		return prefix + winPath, nil
+	}
+	if value == "" || (!force && !IsPathArg(value)) {
+		return arg, nil
+	}
+	if !filepath.IsAbs(value) && !strings.ContainsAny(value, `/\`) {
+		// A bare name would pass through ToWindows as a command.
+		value = "./" + value
+	}
+	winPath, err := c.ToWindowsContext(ctx, value)
+	if err != nil {
+		return "", err
+	}
+	return prefix + winPath, nil
+}
@@ -1,6 +1,9 @@
 package pathconv
 
 import (
+	"context"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/sverrirab/wsl-host-start/internal/protocol"
@@ -66,9 +69,9 @@ func TestApplyAlias(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := conv.applyAlias(tt.input)
+			got := conv.ApplyAlias(tt.input)
 			if got != tt.want {
-				t.Errorf("applyAlias(%q) = %q, want %q", tt.input, got, tt.want)
+				t.Errorf("ApplyAlias(%q) = %q, want %q", tt.input, got, tt.want)
 			}
 		})
 	}
@@ -89,6 +92,8 @@ func TestIsBareCommand(t *testing.T) {
 		{"path/to/file", false},
 		{"http://example.com", false},
 		{"https://google.com", false},
+		{"mailto:a@b.com", false},
+		{"ms-settings:display", false},
 		{".", false},
 		{".hidden", false},
 	}
@@ -139,3 +144,49 @@ func TestToWindowsURLPassthrough(t *testing.T) {
 		}
 	}
 }
+
+func TestTranslateArg(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "src"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "src", "main.go"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(dir)
+
+	conv := NewConverter(nil, map[string]string{"P": `C:\ws`}, true)
+	conv.mounts = &mountTable{mounts: []mount{{point: dir, winRoot: `C:\ws`}}}
+
+	tests := []struct {
+		arg   string
+		force bool
+		want  string
+	}{
+		{"./src/main.go", false, `P:\src\main.go`},
+		{"src/main.go", false, `P:\src\main.go`},
+		{dir + "/src", false, `P:\src`},
+		{"--file=./src/main.go", false, `--file=P:\src\main.go`},
+		{"-o=./src", false, `-o=P:\src`},
+		{"-o=src", false, "-o=src"},
+		{"main.go", false, "main.go"},                     // bare name: resolved via cwd
+		{"./missing/out.txt", false, "./missing/out.txt"}, // does not exist
+		{"edit", false, "edit"},
+		{"--verbose", false, "--verbose"},
+		{"https://example.com/a", false, "https://example.com/a"},
+		{"file://" + dir + "/src/main.go", false, `P:\src\main.go`},
+		{"--url=file://" + dir + "/src", false, `--url=P:\src`},
+		{"out.txt", true, `P:\out.txt`},
+		{"--out=build/x.o", true, `--out=P:\build\x.o`},
+	}
+	for _, tt := range tests {
+		got, err := conv.TranslateArg(context.Background(), tt.arg, tt.force)
+		if err != nil {
+			t.Errorf("TranslateArg(%q, %v): %v", tt.arg, tt.force, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("TranslateArg(%q, %v) = %q, want %q", tt.arg, tt.force, got, tt.want)
+		}
+	}
+}
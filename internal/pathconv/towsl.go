@@ -0,0 +1,133 @@
+package pathconv
+
+import (
+	"context"
+	"os"
+	"slices"
+	"strings"
+)
+
+// ToWSL converts a Windows path to a WSL path. Drive aliases (subst,
+// network and config) are expanded to their targets first, so with
+// P: → C:\dev\workspace, P:\project becomes /mnt/c/dev/workspace/project.
+// Paths under \\wsl.localhost\<distro>\ or \\wsl$\<distro>\ for the running
+// distro become Linux paths.
+func (c *Converter) ToWSL(winPath string) (string, error) {
+	return c.ToWSLContext(context.Background(), winPath)
+}
+
+// ToWSLContext is like ToWSL but kills the wslpath call, if one is needed,
+// when ctx is done first.
+func (c *Converter) ToWSLContext(ctx context.Context, winPath string) (string, error) {
+	winPath = strings.ReplaceAll(winPath, "/", `\`)
+	if linuxPath, ok := distroPath(winPath, os.Getenv("WSL_DISTRO_NAME")); ok {
+		return linuxPath, nil
+	}
+
+	mounts := c.mounts
+	if mounts == nil {
+		mounts = systemMounts()
+	}
+	physical := c.ExpandAlias(winPath)
+	for _, p := range []string{physical, winPath} {
+		if wslPath, ok := mounts.untranslate(p); ok {
+			return wslPath, nil
+		}
+	}
+	return runWslpath(ctx, "-u", physical)
+}
+
+// ExpandAlias replaces an aliased drive letter (subst, network or config)
+// with the path it stands for; the reverse of ApplyAlias. Chains of
+// aliases are followed.
+// Example: P:\project with alias P:→C:\dev\workspace becomes C:\dev\workspace\project
+func (c *Converter) ExpandAlias(winPath string) string {
+	aliases := append(append([]Alias(nil), c.aliases...), c.networks...)
+	for range len(aliases) {
+		if len(winPath) < 2 || winPath[1] != ':' {
+			break
+		}
+		expanded := false
+		for _, a := range aliases {
+			if !strings.EqualFold(strings.TrimSuffix(a.Letter, ":"), winPath[:1]) {
+				continue
+			}
+			rest := strings.TrimLeft(winPath[2:], `\`)
+			winPath = strings.TrimRight(a.Target, `\`)
+			if rest != "" || len(winPath) == 2 {
+				winPath += `\` + rest
+			}
+			expanded = true
+			break
+		}
+		if !expanded {
+			break
+		}
+	}
+	return winPath
+}
+
+// distroPath converts \\wsl.localhost\<distro>\... and \\wsl$\<distro>\...
+// to a Linux path if <distro> is the running distribution.
+func distroPath(winPath, distro string) (string, bool) {
+	if distro == "" {
+		return "", false
+	}
+	lower := strings.ToLower(winPath)
+	var rest string
+	switch {
+	case strings.HasPrefix(lower, `\\wsl.localhost\`):
+		rest = winPath[len(`\\wsl.localhost\`):]
+	case strings.HasPrefix(lower, `\\wsl$\`):
+		rest = winPath[len(`\\wsl$\`):]
+	default:
+		return "", false
+	}
+	name, rest, _ := strings.Cut(rest, `\`)
+	if !strings.EqualFold(name, distro) {
+		return "", false
+	}
+	return "/" + strings.Trim(strings.ReplaceAll(rest, `\`, "/"), "/"), true
+}
+
+// untranslate converts a Windows path to the WSL path of a mount that
+// holds it. Like wslpath, it prefers the mount of the whole drive (e.g.
+// /mnt/c) over mounts of subdirectories. Each candidate is checked by
+// translating it back, so a mount shadowed by another is skipped.
+func (t *mountTable) untranslate(winPath string) (string, bool) {
+	winPath = strings.TrimRight(winPath, `\`)
+	lower := strings.ToLower(winPath)
+
+	var candidates []mount
+	for _, m := range t.mounts {
+		root := strings.ToLower(strings.TrimRight(m.winRoot, `\`))
+		if root != "" && (lower == root || strings.HasPrefix(lower, root+`\`)) {
+			candidates = append(candidates, m)
+		}
+	}
+	if len(candidates) == 0 && !t.hasWindowsMounts() && t.automountRoot != "" &&
+		len(winPath) >= 2 && winPath[1] == ':' && isLetter(winPath[0]) {
+		candidates = append(candidates, mount{point: t.automountRoot + strings.ToLower(winPath[:1]), winRoot: winPath[:2]})
+	}
+	slices.SortStableFunc(candidates, func(a, b mount) int {
+		return len(strings.TrimRight(a.winRoot, `\`)) - len(strings.TrimRight(b.winRoot, `\`))
+	})
+
+	for _, m := range candidates {
+		wslPath := joinWSL(m.point, winPath[len(strings.TrimRight(m.winRoot, `\`)):])
+		back, ok := t.translate(wslPath)
+		if ok && strings.EqualFold(strings.TrimRight(back, `\`), winPath) {
+			return wslPath, true
+		}
+	}
+	return "", false
+}
+
+// joinWSL appends a backslash-separated rest to a WSL mount point.
+func joinWSL(point, rest string) string {
+	rest = strings.Trim(strings.ReplaceAll(rest, `\`, "/"), "/")
+	if rest == "" {
+		return point
+	}
+	return strings.TrimRight(point, "/") + "/" + rest
+}
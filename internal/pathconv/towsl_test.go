@@ -0,0 +1,91 @@
+package pathconv
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sverrirab/wsl-host-start/internal/protocol"
+)
+
+func TestToWSL(t *testing.T) {
+	mounts, err := parseMountinfo(strings.NewReader(wsl2Mountinfo))
+	if err != nil {
+		t.Fatal(err)
+	}
+	drives := []protocol.DriveInfo{
+		{Letter: "P", Type: protocol.DriveSubst, Target: `C:\dev\workspace`},
+		{Letter: "Z", Type: protocol.DriveNetwork, Target: `\\server\share`},
+	}
+	conv := NewConverter(drives, map[string]string{"Q": `P:\sub`}, true)
+	conv.mounts = &mountTable{mounts: mounts}
+	t.Setenv("WSL_DISTRO_NAME", "Ubuntu")
+
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`C:\Users\me\report.pdf`, "/mnt/c/Users/me/report.pdf"},
+		{`c:/Users/me`, "/mnt/c/Users/me"},
+		{`C:\`, "/mnt/c"},
+		{`D:\src\`, "/mnt/d/src"},
+		{`D:\assets\logo.png`, "/mnt/d/assets/logo.png"}, // drive mount wins, as with wslpath
+		{`P:\project\file.c`, "/mnt/c/dev/workspace/project/file.c"},
+		{`P:`, "/mnt/c/dev/workspace"},
+		{`Q:\x`, "/mnt/c/dev/workspace/sub/x"},
+		{`Z:\docs\a.txt`, "/mnt/share/docs/a.txt"},
+		{`\\server\share\docs`, "/mnt/share/docs"},
+		{`\\wsl.localhost\Ubuntu\home\me\project`, "/home/me/project"},
+		{`\\wsl$\ubuntu\etc\hosts`, "/etc/hosts"},
+		{`\\wsl.localhost\Ubuntu`, "/"},
+	}
+	for _, tt := range tests {
+		got, err := conv.ToWSL(tt.input)
+		if err != nil {
+			t.Errorf("ToWSL(%q) error: %v", tt.input, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ToWSL(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestExpandAlias(t *testing.T) {
+	drives := []protocol.DriveInfo{
+		{Letter: "P", Type: protocol.DriveSubst, Target: `C:\dev\workspace`},
+		{Letter: "R", Type: protocol.DriveSubst, Target: `C:\`},
+		{Letter: "Z", Type: protocol.DriveNetwork, Target: `\\server\share`},
+	}
+	conv := NewConverter(drives, map[string]string{"X": `X:\loop`}, true)
+
+	tests := map[string]string{
+		`P:\project`:     `C:\dev\workspace\project`,
+		`p:\project`:     `C:\dev\workspace\project`,
+		`P:\`:            `C:\dev\workspace`,
+		`R:\Windows`:     `C:\Windows`,
+		`R:`:             `C:\`,
+		`Z:\a\b`:         `\\server\share\a\b`,
+		`C:\plain`:       `C:\plain`,
+		`\\server\share`: `\\server\share`,
+		`X:\y`:           `X:\loop\loop\loop\loop\y`, // cycles stop
+	}
+	for in, want := range tests {
+		if got := conv.ExpandAlias(in); got != want {
+			t.Errorf("ExpandAlias(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestUntranslateShadowedMount(t *testing.T) {
+	mounts, _ := parseMountinfo(strings.NewReader(wsl2Mountinfo))
+	table := &mountTable{mounts: mounts}
+	// /mnt/c/tmp is a tmpfs, so C:\tmp is not reachable through /mnt/c.
+	if got, ok := table.untranslate(`C:\tmp\x`); ok {
+		t.Errorf("untranslate(C:\\tmp\\x) = %q, want no mapping", got)
+	}
+	// Without any Windows mounts, the automount root is used.
+	auto := &mountTable{automountRoot: "/win/"}
+	if got, ok := auto.untranslate(`E:\Data`); !ok || got != "/win/e/Data" {
+		t.Errorf("automount untranslate = %q, %v", got, ok)
+	}
+}
@@ -0,0 +1,106 @@
+package pathconv
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// URIScheme returns the lowercased scheme of an absolute URI such as
+// "https://example.com", "mailto:a@b.com" or "ms-settings:display", or ""
+// if s does not start with one. A scheme is a letter followed by letters,
+// digits, "+", "-" or ".", then a colon (RFC 3986). Single letters are
+// Windows drive letters, not schemes.
+func URIScheme(s string) string {
+	i := strings.IndexByte(s, ':')
+	if i < 2 || !isLetter(s[0]) {
+		return ""
+	}
+	for j := 1; j < i; j++ {
+		c := s[j]
+		if !isLetter(c) && (c < '0' || c > '9') && c != '+' && c != '-' && c != '.' {
+			return ""
+		}
+	}
+	return strings.ToLower(s[:i])
+}
+
+// isURI reports whether s is a URI rather than a path: it has a scheme and
+// does not name an existing file (Linux file names may contain colons).
+func isURI(s string) bool {
+	if URIScheme(s) == "" {
+		return false
+	}
+	_, err := os.Lstat(s)
+	return err != nil
+}
+
+// fromFileURI converts a file URI to a Windows path. file:///home/me/a.txt
+// names a WSL path and is translated like any other; file:///C:/a.txt and
+// file://server/share/a.txt already name Windows paths. A query or fragment
+// cannot be part of a path, so with either the result is a file URI of the
+// Windows path instead.
+func (c *Converter) fromFileURI(ctx context.Context, uri string) (string, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "", fmt.Errorf("parsing file URI: %w", err)
+	}
+	if u.Opaque != "" || u.Path == "" {
+		return "", fmt.Errorf("%s: not an absolute file URI", uri)
+	}
+
+	winPath, ok := windowsPathOf(u)
+	if !ok {
+		winPath, err = c.ToWindowsContext(ctx, u.Path)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	if u.RawQuery == "" && u.Fragment == "" {
+		return winPath, nil
+	}
+	return windowsFileURI(winPath, u.RawQuery, u.Fragment), nil
+}
+
+// FileURIPath returns the Windows path a file URI names, for checks on the
+// host: file:///C:/a.exe is C:\a.exe and file://server/share/a.exe is
+// \\server\share\a.exe. A query or fragment is ignored. It returns false
+// for any other URI, including file URIs of WSL paths, which only the WSL
+// side can convert.
+func FileURIPath(uri string) (string, bool) {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" || u.Opaque != "" || u.Path == "" {
+		return "", false
+	}
+	return windowsPathOf(u)
+}
+
+// windowsPathOf returns the Windows path of a file URL whose host is a
+// server or whose path starts with a drive letter.
+func windowsPathOf(u *url.URL) (string, bool) {
+	drivePath := strings.TrimPrefix(u.Path, "/")
+	switch {
+	case u.Host != "" && !strings.EqualFold(u.Host, "localhost"):
+		return `\\` + u.Host + strings.ReplaceAll(u.Path, "/", `\`), true
+	case len(drivePath) >= 2 && drivePath[1] == ':' && isLetter(drivePath[0]):
+		return driveRoot(strings.ReplaceAll(drivePath, "/", `\`)), true
+	}
+	return "", false
+}
+
+// windowsFileURI returns the file URI of a Windows path, e.g.
+// file:///C:/dir/a.html or file://server/share/a.html.
+func windowsFileURI(winPath, rawQuery, fragment string) string {
+	u := &url.URL{Scheme: "file", RawQuery: rawQuery, Fragment: fragment}
+	slashed := strings.ReplaceAll(winPath, `\`, "/")
+	if rest, ok := strings.CutPrefix(slashed, "//"); ok {
+		u.Host, u.Path, _ = strings.Cut(rest, "/")
+		u.Path = "/" + u.Path
+	} else {
+		u.Path = "/" + slashed
+	}
+	return u.String()
+}
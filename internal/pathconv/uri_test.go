@@ -0,0 +1,126 @@
+package pathconv
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestURIScheme(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"https://example.com", "https"},
+		{"HTTP://EXAMPLE.COM", "http"},
+		{"mailto:a@b.com", "mailto"},
+		{"ms-settings:display", "ms-settings"},
+		{"vscode://file/c:/x", "vscode"},
+		{"git+ssh://host/repo", "git+ssh"},
+		{"file:///home/me/a.txt", "file"},
+		{`C:\Windows`, ""},
+		{"C:/Windows", ""},
+		{"./a:b", ""},
+		{"1http://x", ""},
+		{"my_scheme:x", ""},
+		{"notepad.exe", ""},
+		{":x", ""},
+	}
+	for _, tt := range tests {
+		if got := URIScheme(tt.input); got != tt.want {
+			t.Errorf("URIScheme(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestToWindowsURIPassthrough(t *testing.T) {
+	conv := NewConverter(nil, nil, true)
+	for _, uri := range []string{
+		"mailto:a@b.com?subject=hi",
+		"ms-settings:display",
+		"vscode://file/home/me/a.go:12",
+		"zoommtg://zoom.us/join?confno=1",
+	} {
+		got, err := conv.ToWindows(uri)
+		if err != nil {
+			t.Errorf("ToWindows(%q) error: %v", uri, err)
+			continue
+		}
+		if got != uri {
+			t.Errorf("ToWindows(%q) = %q, want passthrough", uri, got)
+		}
+	}
+}
+
+func TestToWindowsFileURI(t *testing.T) {
+	dir := t.TempDir()
+	conv := NewConverter(nil, map[string]string{"P": `C:\ws`}, true)
+	conv.mounts = &mountTable{mounts: []mount{{point: dir, winRoot: `C:\ws`}}}
+
+	tests := []struct {
+		uri  string
+		want string
+	}{
+		{"file://" + dir + "/report.html", `P:\report.html`},
+		{"file://localhost" + dir + "/my%20report.html", `P:\my report.html`},
+		{"file://" + dir + "/doc.html#intro", "file:///P:/doc.html#intro"},
+		{"file:///C:/Users/me/a.txt", `C:\Users\me\a.txt`},
+		{"file:///D:", `D:\`},
+		{"file://server/share/a.txt", `\\server\share\a.txt`},
+		{"file://server/share/a.html?x=1", "file://server/share/a.html?x=1"},
+	}
+	for _, tt := range tests {
+		got, err := conv.ToWindows(tt.uri)
+		if err != nil {
+			t.Errorf("ToWindows(%q) error: %v", tt.uri, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ToWindows(%q) = %q, want %q", tt.uri, got, tt.want)
+		}
+	}
+
+	if _, err := conv.ToWindows("file:report.html"); err == nil {
+		t.Error("relative file URI: expected an error")
+	}
+}
+
+func TestToWindowsFileWithColon(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	if err := os.WriteFile(filepath.Join(dir, "notes:2024.txt"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	conv := NewConverter(nil, nil, false)
+	conv.mounts = &mountTable{mounts: []mount{{point: dir, winRoot: `C:\ws`}}}
+
+	got, err := conv.ToWindowsContext(context.Background(), "notes:2024.txt")
+	if err != nil {
+		t.Fatalf("ToWindows: %v", err)
+	}
+	if got != `C:\ws\notes:2024.txt` {
+		t.Errorf("ToWindows = %q, want the existing file translated as a path", got)
+	}
+}
+
+func TestFileURIPath(t *testing.T) {
+	tests := []struct {
+		uri    string
+		want   string
+		wantOK bool
+	}{
+		{"file:///C:/Windows/System32/cmd.exe", `C:\Windows\System32\cmd.exe`, true},
+		{"file:///c:/my%20docs/a.html#top", `c:\my docs\a.html`, true},
+		{"file://server/share/a.txt?x=1", `\\server\share\a.txt`, true},
+		{"file:///home/me/a.txt", "", false},
+		{"file:a.txt", "", false},
+		{"https://example.com", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := FileURIPath(tt.uri)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("FileURIPath(%q) = %q, %v; want %q, %v", tt.uri, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
@@ -1,21 +1,26 @@
 package pathconv
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os/exec"
 	"strings"
 )
 
-// runWslpath calls the wslpath utility to translate a WSL path to a Windows path.
-func runWslpath(wslPath string) (string, error) {
-	cmd := exec.Command("wslpath", "-w", wslPath)
+// runWslpath calls the wslpath utility to translate a path: mode "-w"
+// converts a WSL path to a Windows path, "-u" the reverse.
+func runWslpath(ctx context.Context, mode, path string) (string, error) {
+	cmd := exec.CommandContext(ctx, "wslpath", mode, path)
 	out, err := cmd.Output()
 	if err != nil {
 		if errors.Is(err, exec.ErrNotFound) {
 			return "", fmt.Errorf("wslpath not found: not running in WSL?")
 		}
-		return "", fmt.Errorf("wslpath -w %q failed: %w", wslPath, err)
+		if ctx.Err() != nil {
+			return "", fmt.Errorf("wslpath %s %q: %w", mode, path, ctx.Err())
+		}
+		return "", fmt.Errorf("wslpath %s %q failed: %w", mode, path, err)
 	}
 	return strings.TrimSpace(string(out)), nil
 }
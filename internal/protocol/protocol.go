@@ -1,6 +1,76 @@
 // Package protocol defines the JSON types shared between the WSL CLI and Windows helper.
 package protocol
 
+import "encoding/json"
+
+// ProtocolVersion is the wire protocol version spoken by this build. It is
+// bumped when a request or response changes in a way an older peer cannot
+// safely ignore. Helpers that predate the handshake are treated as version 0.
+const ProtocolVersion = 3
+
+// MinProtocolVersion is the oldest helper protocol the WSL CLI can drive.
+const MinProtocolVersion = 0
+
+// Capability names advertised by the helper in HelloResponse. The WSL CLI
+// only uses a feature when the helper advertises the matching capability.
+const (
+	CapLaunch    = "launch"    // --launch: ShellExecuteEx with a JSON response
+	CapExec      = "exec"      // --exec: stdio passthrough, child exit code
+	CapDrives    = "drives"    // --drives: drive letter enumeration
+	CapEnvVars   = "envVars"   // LaunchRequest.EnvVars is applied to the child
+	CapRequest   = "request"   // --request combined exchange, LaunchRequest.ResolveAliases
+	CapServe     = "serve"     // --serve: persistent JSON-RPC session over stdio
+	CapMulti     = "multi"     // Request.Launches: several targets in one --request
+	CapCmdLine   = "cmdLine"   // LaunchRequest.CmdLine is passed to the program verbatim
+	CapLaunchEnv = "launchEnv" // LaunchRequest.EnvVars is applied by ShellExecuteEx launches too
+	CapAdHocEnv  = "adHocEnv"  // LaunchRequest.AdHocEnv is honoured by the host env policy
+)
+
+// Capabilities returns the capabilities implemented by this build.
+func Capabilities() []string {
+	return []string{CapLaunch, CapExec, CapDrives, CapEnvVars, CapRequest, CapServe, CapMulti, CapCmdLine, CapLaunchEnv, CapAdHocEnv}
+}
+
+// HelloResponse is returned by the Windows helper in --hello mode.
+type HelloResponse struct {
+	ProtocolVersion int      `json:"protocolVersion"`
+	Version         string   `json:"version"`
+	Capabilities    []string `json:"capabilities"`
+}
+
+// LegacyHello describes a helper that predates --hello. Those helpers
+// implemented launch, exec, drives and env forwarding, so the handshake
+// can still succeed against them.
+func LegacyHello(version string) *HelloResponse {
+	return &HelloResponse{
+		ProtocolVersion: 0,
+		Version:         version,
+		Capabilities:    []string{CapLaunch, CapExec, CapDrives, CapEnvVars},
+	}
+}
+
+// Has reports whether the helper advertises the given capability.
+func (h *HelloResponse) Has(capability string) bool {
+	for _, c := range h.Capabilities {
+		if c == capability {
+			return true
+		}
+	}
+	return false
+}
+
+// Missing returns the required capabilities the helper does not advertise,
+// in the order they were given.
+func (h *HelloResponse) Missing(required ...string) []string {
+	var missing []string
+	for _, c := range required {
+		if !h.Has(c) {
+			missing = append(missing, c)
+		}
+	}
+	return missing
+}
+
 // LaunchRequest is sent from the WSL CLI to the Windows helper over stdin.
 type LaunchRequest struct {
 	File    string            `json:"file"`
@@ -10,6 +80,21 @@ type LaunchRequest struct {
 	Show    string            `json:"show"`
 	Wait    bool              `json:"wait"`
 	EnvVars map[string]string `json:"envVars,omitempty"`
+
+	// ResolveAliases asks the helper to apply drive aliases to File and
+	// WorkDir itself. The WSL side sets this when its drive cache is stale,
+	// so the launch does not need a separate --drives round trip.
+	ResolveAliases bool `json:"resolveAliases,omitempty"`
+
+	// CmdLine, if set, replaces Args: it is passed to the program as is,
+	// for programs that do not split their command line with the usual
+	// MSVCRT rules. Otherwise the helper quotes Args (see cmdline.Join).
+	CmdLine string `json:"cmdLine,omitempty"`
+
+	// AdHocEnv names the EnvVars given for this launch only (wstart -env).
+	// The helper accepts them without a forward entry if its config allows
+	// ad-hoc variables; its block list still applies.
+	AdHocEnv []string `json:"adHocEnv,omitempty"`
 }
 
 // LaunchResponse is returned from the Windows helper to the WSL CLI over stdout.
@@ -17,7 +102,132 @@ type LaunchResponse struct {
 	ExitCode int    `json:"exitCode"`
 	PID      int    `json:"pid"`
 	Error    string `json:"error,omitempty"`
-	ErrCode  int    `json:"errCode,omitempty"`
+	// ErrCode is the raw Windows code (SE_ERR_* or Win32 error) when available.
+	ErrCode int `json:"errCode,omitempty"`
+	// Category classifies Error (one of the Err* constants).
+	Category string `json:"category,omitempty"`
+	// Rule is the offending deny list entry or allowlist rule, if any.
+	Rule string `json:"rule,omitempty"`
+	// Path is the offending file: the allowlist or config file for policy
+	// and signature errors, the target for file-not-found.
+	Path string `json:"path,omitempty"`
+	// DroppedEnv lists the EnvVars the helper removed under its own [env]
+	// policy before launching, e.g. "PATH (protected)".
+	DroppedEnv []string `json:"droppedEnv,omitempty"`
+}
+
+// Exit codes reserved for failures that originate in wstart or the helper
+// rather than in the launched program, following env(1) and timeout(1).
+// wstart-host --exec uses them too, so they pass through unchanged.
+const (
+	ExitInternal = 125 // wstart itself failed: usage, helper not found, interop, protocol
+	ExitDenied   = 126 // denied by policy: deny list, allowlist, config signature, UAC
+	ExitNotFound = 127 // target not found, or nothing is associated with it
+)
+
+// ExitCodeFor returns the reserved exit code for an error category.
+func ExitCodeFor(category string) int {
+	switch category {
+	case ErrDeniedDenylist, ErrDeniedAllowlist, ErrHashMismatch, ErrSignatureInvalid, ErrElevationCancelled:
+		return ExitDenied
+	case ErrFileNotFound, ErrNoAssociation:
+		return ExitNotFound
+	default:
+		return ExitInternal
+	}
+}
+
+// Error categories reported in LaunchResponse.Category.
+const (
+	ErrDeniedDenylist     = "denied-by-denylist"
+	ErrDeniedAllowlist    = "denied-by-allowlist"
+	ErrHashMismatch       = "hash-mismatch"
+	ErrSignatureInvalid   = "signature-invalid"
+	ErrFileNotFound       = "file-not-found"
+	ErrNoAssociation      = "no-association"
+	ErrNotWaitable        = "not-waitable"
+	ErrElevationCancelled = "elevation-cancelled"
+	ErrInternal           = "internal"
+)
+
+// Request is read by the Windows helper in --request mode. Any combination
+// of sections may be set; the helper answers all of them in one Response so
+// a launch costs a single Windows process spawn.
+type Request struct {
+	// Require lists capabilities the client depends on. The helper refuses
+	// the whole request if it lacks any of them.
+	Require []string       `json:"require,omitempty"`
+	Hello   bool           `json:"hello,omitempty"`
+	Drives  bool           `json:"drives,omitempty"`
+	Launch  *LaunchRequest `json:"launch,omitempty"`
+	// Launches are run in order after Launch; each gets its own result.
+	Launches []LaunchRequest `json:"launches,omitempty"`
+}
+
+// Response is returned by the Windows helper in --request mode.
+type Response struct {
+	Hello  *HelloResponse  `json:"hello,omitempty"`
+	Drives *DrivesResponse `json:"drives,omitempty"`
+	Launch *LaunchResponse `json:"launch,omitempty"`
+	// Launches holds one result per Request.Launches entry, in order.
+	Launches []LaunchResponse `json:"launches,omitempty"`
+	// Error is set when the request as a whole could not be served
+	// (missing capability, config signature failure).
+	Error string `json:"error,omitempty"`
+}
+
+// JSON-RPC methods served by the Windows helper in --serve mode.
+const (
+	MethodHello  = "hello"  // no params → HelloResponse
+	MethodDrives = "drives" // no params → DrivesResponse
+	MethodLaunch = "launch" // LaunchRequest → LaunchResponse
+	MethodCheck  = "check"  // LaunchRequest → CheckResponse (policy only, nothing is launched)
+)
+
+// JSON-RPC 2.0 error codes used in RPCError.Code.
+const (
+	RPCParseError     = -32700
+	RPCInvalidRequest = -32600
+	RPCMethodNotFound = -32601
+	RPCInvalidParams  = -32602
+	RPCServerError    = -32000
+)
+
+// RPCRequest is one newline-delimited JSON-RPC 2.0 request in --serve mode.
+type RPCRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      int64           `json:"id"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// RPCResponse answers the RPCRequest with the same ID. Exactly one of
+// Result and Error is set.
+type RPCResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      int64           `json:"id"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *RPCError       `json:"error,omitempty"`
+}
+
+// RPCError is a per-request failure. The session stays usable.
+type RPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *RPCError) Error() string {
+	return e.Message
+}
+
+// CheckResponse is the result of the "check" method: whether the request
+// would pass the helper's signature, deny list and allowlist checks.
+type CheckResponse struct {
+	Allowed  bool   `json:"allowed"`
+	Reason   string `json:"reason,omitempty"`
+	Category string `json:"category,omitempty"`
+	Rule     string `json:"rule,omitempty"`
+	Path     string `json:"path,omitempty"`
 }
 
 // DriveInfo describes a single Windows drive letter.
@@ -0,0 +1,70 @@
+package protocol
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestHelloHas(t *testing.T) {
+	h := &HelloResponse{Capabilities: []string{CapLaunch, CapDrives}}
+
+	if !h.Has(CapLaunch) {
+		t.Error("expected launch capability")
+	}
+	if h.Has(CapExec) {
+		t.Error("did not expect exec capability")
+	}
+}
+
+func TestHelloMissing(t *testing.T) {
+	h := &HelloResponse{Capabilities: []string{CapLaunch, CapDrives}}
+
+	got := h.Missing(CapExec, CapLaunch, CapEnvVars)
+	want := []string{CapExec, CapEnvVars}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Missing = %v, want %v", got, want)
+	}
+	if got := h.Missing(CapLaunch); got != nil {
+		t.Errorf("Missing(launch) = %v, want nil", got)
+	}
+}
+
+func TestLegacyHello(t *testing.T) {
+	h := LegacyHello("v0.3.0")
+	if h.ProtocolVersion != 0 {
+		t.Errorf("ProtocolVersion = %d, want 0", h.ProtocolVersion)
+	}
+	if h.Version != "v0.3.0" {
+		t.Errorf("Version = %q", h.Version)
+	}
+	if missing := h.Missing(CapLaunch, CapExec, CapDrives, CapEnvVars); missing != nil {
+		t.Errorf("legacy helper should support the original feature set, missing %v", missing)
+	}
+}
+
+func TestCapabilitiesSupersetOfLegacy(t *testing.T) {
+	current := &HelloResponse{Capabilities: Capabilities()}
+	if missing := current.Missing(LegacyHello("").Capabilities...); missing != nil {
+		t.Errorf("current build dropped legacy capabilities: %v", missing)
+	}
+}
+
+func TestExitCodeFor(t *testing.T) {
+	tests := map[string]int{
+		ErrDeniedDenylist:     ExitDenied,
+		ErrDeniedAllowlist:    ExitDenied,
+		ErrHashMismatch:       ExitDenied,
+		ErrSignatureInvalid:   ExitDenied,
+		ErrElevationCancelled: ExitDenied,
+		ErrFileNotFound:       ExitNotFound,
+		ErrNoAssociation:      ExitNotFound,
+		ErrNotWaitable:        ExitInternal,
+		ErrInternal:           ExitInternal,
+		"":                    ExitInternal,
+	}
+	for category, want := range tests {
+		if got := ExitCodeFor(category); got != want {
+			t.Errorf("ExitCodeFor(%q) = %d, want %d", category, got, want)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+// Package rpc implements the newline-delimited JSON-RPC 2.0 framing used by
+// wstart-host --serve and its WSL-side client. It has no platform
+// dependencies so both ends can be tested on Linux.
+package rpc
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/sverrirab/wsl-host-start/internal/protocol"
+)
+
+const jsonrpcVersion = "2.0"
+
+// maxLine bounds a single request or response line.
+const maxLine = 16 << 20
+
+// Handler serves one method call. Returning a *protocol.RPCError selects
+// the error code; any other error is reported as RPCServerError.
+type Handler func(method string, params json.RawMessage) (any, error)
+
+// Serve reads requests from r until EOF, dispatching each to h and writing
+// the correlated response to w. Malformed lines get an error response and
+// do not end the session. Serve returns nil on clean EOF.
+func Serve(r io.Reader, w io.Writer, h Handler) error {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), maxLine)
+	enc := json.NewEncoder(w)
+
+	for sc.Scan() {
+		line := sc.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+
+		var req protocol.RPCRequest
+		if err := json.Unmarshal(line, &req); err != nil {
+			resp := errorResponse(0, &protocol.RPCError{Code: protocol.RPCParseError, Message: err.Error()})
+			if err := enc.Encode(resp); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := enc.Encode(dispatch(&req, h)); err != nil {
+			return err
+		}
+	}
+	return sc.Err()
+}
+
+func dispatch(req *protocol.RPCRequest, h Handler) *protocol.RPCResponse {
+	if req.Method == "" {
+		return errorResponse(req.ID, &protocol.RPCError{Code: protocol.RPCInvalidRequest, Message: "missing method"})
+	}
+
+	result, err := h(req.Method, req.Params)
+	if err != nil {
+		var rpcErr *protocol.RPCError
+		if !errors.As(err, &rpcErr) {
+			rpcErr = &protocol.RPCError{Code: protocol.RPCServerError, Message: err.Error()}
+		}
+		return errorResponse(req.ID, rpcErr)
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		return errorResponse(req.ID, &protocol.RPCError{Code: protocol.RPCServerError, Message: fmt.Sprintf("encoding result: %v", err)})
+	}
+	return &protocol.RPCResponse{JSONRPC: jsonrpcVersion, ID: req.ID, Result: data}
+}
+
+func errorResponse(id int64, err *protocol.RPCError) *protocol.RPCResponse {
+	return &protocol.RPCResponse{JSONRPC: jsonrpcVersion, ID: id, Error: err}
+}
+
+// MethodNotFound returns the error a Handler should report for an unknown method.
+func MethodNotFound(method string) error {
+	return &protocol.RPCError{Code: protocol.RPCMethodNotFound, Message: fmt.Sprintf("unknown method %q", method)}
+}
+
+// InvalidParams returns the error a Handler should report when params do not decode.
+func InvalidParams(err error) error {
+	return &protocol.RPCError{Code: protocol.RPCInvalidParams, Message: fmt.Sprintf("invalid params: %v", err)}
+}
+
+// Client issues sequential calls over a request writer and response reader,
+// typically the stdin/stdout pipes of wstart-host --serve.
+type Client struct {
+	mu     sync.Mutex
+	w      io.Writer
+	sc     *bufio.Scanner
+	nextID int64
+}
+
+// NewClient creates a client that writes requests to w and reads responses from r.
+func NewClient(r io.Reader, w io.Writer) *Client {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), maxLine)
+	return &Client{w: w, sc: sc}
+}
+
+// Call invokes method with params (may be nil) and decodes the result into
+// result (may be nil). A per-request failure is returned as *protocol.RPCError.
+func (c *Client) Call(method string, params, result any) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.nextID++
+	req := protocol.RPCRequest{JSONRPC: jsonrpcVersion, ID: c.nextID, Method: method}
+	if params != nil {
+		data, err := json.Marshal(params)
+		if err != nil {
+			return fmt.Errorf("encoding %s params: %w", method, err)
+		}
+		req.Params = data
+	}
+
+	line, err := json.Marshal(&req)
+	if err != nil {
+		return fmt.Errorf("encoding %s request: %w", method, err)
+	}
+	if _, err := c.w.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("sending %s request: %w", method, err)
+	}
+
+	if !c.sc.Scan() {
+		if err := c.sc.Err(); err != nil {
+			return fmt.Errorf("reading %s response: %w", method, err)
+		}
+		return fmt.Errorf("reading %s response: %w", method, io.ErrUnexpectedEOF)
+	}
+
+	var resp protocol.RPCResponse
+	if err := json.Unmarshal(c.sc.Bytes(), &resp); err != nil {
+		return fmt.Errorf("decoding %s response: %w", method, err)
+	}
+	if resp.ID != req.ID {
+		return fmt.Errorf("%s response id %d does not match request id %d", method, resp.ID, req.ID)
+	}
+	if resp.Error != nil {
+		return resp.Error
+	}
+	if result != nil {
+		if err := json.Unmarshal(resp.Result, result); err != nil {
+			return fmt.Errorf("decoding %s result: %w", method, err)
+		}
+	}
+	return nil
+}
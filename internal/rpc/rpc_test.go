@@ -0,0 +1,156 @@
+package rpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/sverrirab/wsl-host-start/internal/protocol"
+)
+
+// echoHandler answers "echo" with its params, "fail" with a server error
+// and everything else with method-not-found.
+func echoHandler(method string, params json.RawMessage) (any, error) {
+	switch method {
+	case "echo":
+		var v map[string]string
+		if err := json.Unmarshal(params, &v); err != nil {
+			return nil, InvalidParams(err)
+		}
+		return v, nil
+	case "fail":
+		return nil, fmt.Errorf("something broke")
+	default:
+		return nil, MethodNotFound(method)
+	}
+}
+
+// startServer runs Serve over in-memory pipes and returns a connected client.
+// Closing the returned writer signals EOF; the done channel yields Serve's result.
+func startServer(t *testing.T) (*Client, io.Closer, <-chan error) {
+	t.Helper()
+	reqR, reqW := io.Pipe()
+	respR, respW := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		err := Serve(reqR, respW, echoHandler)
+		respW.Close()
+		done <- err
+	}()
+	return NewClient(respR, reqW), reqW, done
+}
+
+func TestCallRoundTrip(t *testing.T) {
+	c, stdin, done := startServer(t)
+
+	var got map[string]string
+	if err := c.Call("echo", map[string]string{"file": "a.pdf"}, &got); err != nil {
+		t.Fatalf("Call: %v", err)
+	}
+	if got["file"] != "a.pdf" {
+		t.Errorf("result = %v", got)
+	}
+
+	// A second call on the same session uses the next ID.
+	if err := c.Call("echo", map[string]string{"file": "b.pdf"}, &got); err != nil {
+		t.Fatalf("second Call: %v", err)
+	}
+	if got["file"] != "b.pdf" {
+		t.Errorf("result = %v", got)
+	}
+
+	stdin.Close()
+	if err := <-done; err != nil {
+		t.Errorf("Serve should return nil on EOF, got %v", err)
+	}
+}
+
+func TestCallPerRequestErrors(t *testing.T) {
+	c, stdin, done := startServer(t)
+	defer func() {
+		stdin.Close()
+		<-done
+	}()
+
+	tests := []struct {
+		method string
+		params any
+		code   int
+	}{
+		{"fail", nil, protocol.RPCServerError},
+		{"nope", nil, protocol.RPCMethodNotFound},
+		{"echo", []int{1}, protocol.RPCInvalidParams},
+	}
+	for _, tt := range tests {
+		err := c.Call(tt.method, tt.params, nil)
+		var rpcErr *protocol.RPCError
+		if !errors.As(err, &rpcErr) {
+			t.Fatalf("Call(%s) err = %v, want *RPCError", tt.method, err)
+		}
+		if rpcErr.Code != tt.code {
+			t.Errorf("Call(%s) code = %d, want %d", tt.method, rpcErr.Code, tt.code)
+		}
+	}
+
+	// The session survives errors.
+	if err := c.Call("echo", map[string]string{}, nil); err != nil {
+		t.Errorf("call after errors: %v", err)
+	}
+}
+
+func TestServeCorrelatesIDs(t *testing.T) {
+	in := strings.Join([]string{
+		`{"jsonrpc":"2.0","id":41,"method":"echo","params":{"x":"1"}}`,
+		``,
+		`not json`,
+		`{"jsonrpc":"2.0","id":42,"method":"fail"}`,
+		`{"jsonrpc":"2.0","id":43}`,
+	}, "\n")
+	var out bytes.Buffer
+	if err := Serve(strings.NewReader(in), &out, echoHandler); err != nil {
+		t.Fatalf("Serve: %v", err)
+	}
+
+	dec := json.NewDecoder(&out)
+	var resps []protocol.RPCResponse
+	for dec.More() {
+		var r protocol.RPCResponse
+		if err := dec.Decode(&r); err != nil {
+			t.Fatal(err)
+		}
+		resps = append(resps, r)
+	}
+	if len(resps) != 4 {
+		t.Fatalf("got %d responses, want 4 (blank line skipped)", len(resps))
+	}
+
+	if resps[0].ID != 41 || resps[0].Error != nil || string(resps[0].Result) != `{"x":"1"}` {
+		t.Errorf("resp[0] = %+v", resps[0])
+	}
+	if resps[1].Error == nil || resps[1].Error.Code != protocol.RPCParseError {
+		t.Errorf("resp[1] = %+v, want parse error", resps[1])
+	}
+	if resps[2].ID != 42 || resps[2].Error == nil || resps[2].Error.Message != "something broke" {
+		t.Errorf("resp[2] = %+v", resps[2])
+	}
+	if resps[3].ID != 43 || resps[3].Error == nil || resps[3].Error.Code != protocol.RPCInvalidRequest {
+		t.Errorf("resp[3] = %+v, want invalid request", resps[3])
+	}
+	for _, r := range resps {
+		if r.JSONRPC != "2.0" {
+			t.Errorf("jsonrpc = %q", r.JSONRPC)
+		}
+	}
+}
+
+func TestCallUnexpectedEOF(t *testing.T) {
+	c := NewClient(strings.NewReader(""), io.Discard)
+	err := c.Call("echo", nil, nil)
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("err = %v, want unexpected EOF", err)
+	}
+}
@@ -0,0 +1,94 @@
+package shellexec
+
+import (
+	"os"
+	"sort"
+	"strings"
+)
+
+// mergeEnv returns environ with vars applied. A variable that is already
+// set is replaced in place, matching names case-insensitively as Windows
+// does; new variables are appended in name order. Entries whose name
+// starts with "=" (per-drive working directories such as "=C:=C:\dev")
+// are kept as they are.
+func mergeEnv(environ []string, vars map[string]string) []string {
+	out := make([]string, 0, len(environ)+len(vars))
+	applied := make(map[string]bool, len(vars))
+	upper := make(map[string]string, len(vars))
+	for k := range vars {
+		upper[strings.ToUpper(k)] = k
+	}
+	for _, kv := range environ {
+		k, ok := upper[strings.ToUpper(envName(kv))]
+		switch {
+		case !ok:
+			out = append(out, kv)
+		case !applied[k]:
+			out = append(out, k+"="+vars[k])
+			applied[k] = true
+		}
+		// Later duplicates of a replaced variable are dropped.
+	}
+
+	added := make([]string, 0, len(vars))
+	for k := range vars {
+		if !applied[k] {
+			added = append(added, k)
+		}
+	}
+	sort.Strings(added)
+	for _, k := range added {
+		out = append(out, k+"="+vars[k])
+	}
+	return out
+}
+
+// envName returns the name of a NAME=value entry. A leading "=" belongs
+// to the name.
+func envName(kv string) string {
+	start := 0
+	if strings.HasPrefix(kv, "=") {
+		start = 1
+	}
+	if i := strings.IndexByte(kv[start:], '='); i >= 0 {
+		return kv[:start+i]
+	}
+	return kv
+}
+
+// setEnv applies vars to the helper's own environment, so that a process
+// started by ShellExecuteEx inherits them, and returns a function that
+// restores the previous values. Variables already applied are restored if
+// one of them cannot be set.
+func setEnv(vars map[string]string) (restore func(), err error) {
+	type saved struct {
+		name  string
+		value string
+		set   bool
+	}
+	var prev []saved
+	restore = func() {
+		for i := len(prev) - 1; i >= 0; i-- {
+			if prev[i].set {
+				_ = os.Setenv(prev[i].name, prev[i].value)
+			} else {
+				_ = os.Unsetenv(prev[i].name)
+			}
+		}
+	}
+
+	names := make([]string, 0, len(vars))
+	for k := range vars {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	for _, k := range names {
+		value, set := os.LookupEnv(k)
+		if err := os.Setenv(k, vars[k]); err != nil {
+			restore()
+			return nil, err
+		}
+		prev = append(prev, saved{name: k, value: value, set: set})
+	}
+	return restore, nil
+}
@@ -0,0 +1,82 @@
+package shellexec
+
+import (
+	"os"
+	"slices"
+	"testing"
+)
+
+func TestMergeEnv(t *testing.T) {
+	environ := []string{
+		"=C:=C:\\dev",
+		"Path=C:\\Windows",
+		"P4PORT=old:1666",
+		"p4port=dup:1666",
+		"USERNAME=me",
+	}
+	got := mergeEnv(environ, map[string]string{
+		"P4PORT":   "ssl:perforce:1666",
+		"P4CLIENT": "ws",
+		"EDITOR":   "code",
+	})
+	want := []string{
+		"=C:=C:\\dev",
+		"Path=C:\\Windows",
+		"P4PORT=ssl:perforce:1666",
+		"USERNAME=me",
+		"EDITOR=code",
+		"P4CLIENT=ws",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("mergeEnv =\n  %q\nwant\n  %q", got, want)
+	}
+}
+
+func TestMergeEnvNoVars(t *testing.T) {
+	environ := []string{"A=1", "B=2"}
+	if got := mergeEnv(environ, nil); !slices.Equal(got, environ) {
+		t.Errorf("mergeEnv(nil) = %q, want %q", got, environ)
+	}
+}
+
+func TestEnvName(t *testing.T) {
+	tests := map[string]string{
+		"PATH=C:\\Windows": "PATH",
+		"=C:=C:\\dev":      "=C:",
+		"EMPTY=":           "EMPTY",
+		"NOVALUE":          "NOVALUE",
+		"A=B=C":            "A",
+	}
+	for kv, want := range tests {
+		if got := envName(kv); got != want {
+			t.Errorf("envName(%q) = %q, want %q", kv, got, want)
+		}
+	}
+}
+
+func TestSetEnvRestores(t *testing.T) {
+	t.Setenv("WSTART_TEST_SET", "before")
+	os.Unsetenv("WSTART_TEST_NEW")
+
+	restore, err := setEnv(map[string]string{
+		"WSTART_TEST_SET": "during",
+		"WSTART_TEST_NEW": "added",
+	})
+	if err != nil {
+		t.Fatalf("setEnv: %v", err)
+	}
+	if got := os.Getenv("WSTART_TEST_SET"); got != "during" {
+		t.Errorf("WSTART_TEST_SET = %q while applied, want %q", got, "during")
+	}
+	if got := os.Getenv("WSTART_TEST_NEW"); got != "added" {
+		t.Errorf("WSTART_TEST_NEW = %q while applied, want %q", got, "added")
+	}
+
+	restore()
+	if got := os.Getenv("WSTART_TEST_SET"); got != "before" {
+		t.Errorf("WSTART_TEST_SET = %q after restore, want %q", got, "before")
+	}
+	if _, ok := os.LookupEnv("WSTART_TEST_NEW"); ok {
+		t.Errorf("WSTART_TEST_NEW still set after restore")
+	}
+}
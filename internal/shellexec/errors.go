@@ -0,0 +1,36 @@
+package shellexec
+
+import "github.com/sverrirab/wsl-host-start/internal/protocol"
+
+// ShellExecuteEx failure codes. hInstApp carries an SE_ERR_* value and
+// GetLastError a Win32 error; either may identify the failure.
+const (
+	seErrFNF             = 2  // SE_ERR_FNF / ERROR_FILE_NOT_FOUND
+	seErrPNF             = 3  // SE_ERR_PNF / ERROR_PATH_NOT_FOUND
+	seErrAssocIncomplete = 27 // SE_ERR_ASSOCINCOMPLETE
+	seErrNoAssoc         = 31 // SE_ERR_NOASSOC
+
+	errorBadPathname = 161  // ERROR_BAD_PATHNAME
+	errorNoAssoc     = 1155 // ERROR_NO_ASSOCIATION
+	errorCancelled   = 1223 // ERROR_CANCELLED (user dismissed the UAC prompt)
+)
+
+// classifyError maps a ShellExecuteEx failure to a protocol error category.
+// seErr is the SE_ERR_* value from hInstApp, lastErr the Win32 error code.
+func classifyError(seErr, lastErr int) string {
+	switch lastErr {
+	case errorCancelled:
+		return protocol.ErrElevationCancelled
+	case errorNoAssoc:
+		return protocol.ErrNoAssociation
+	case seErrFNF, seErrPNF, errorBadPathname:
+		return protocol.ErrFileNotFound
+	}
+	switch seErr {
+	case seErrFNF, seErrPNF:
+		return protocol.ErrFileNotFound
+	case seErrNoAssoc, seErrAssocIncomplete:
+		return protocol.ErrNoAssociation
+	}
+	return protocol.ErrInternal
+}
@@ -0,0 +1,30 @@
+package shellexec
+
+import (
+	"testing"
+
+	"github.com/sverrirab/wsl-host-start/internal/protocol"
+)
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		name    string
+		seErr   int
+		lastErr int
+		want    string
+	}{
+		{"SE_ERR_FNF", seErrFNF, 0, protocol.ErrFileNotFound},
+		{"ERROR_PATH_NOT_FOUND", 0, seErrPNF, protocol.ErrFileNotFound},
+		{"SE_ERR_NOASSOC", seErrNoAssoc, 0, protocol.ErrNoAssociation},
+		{"ERROR_NO_ASSOCIATION wins over SE_ERR", seErrAssocIncomplete, errorNoAssoc, protocol.ErrNoAssociation},
+		{"UAC cancelled", 5, errorCancelled, protocol.ErrElevationCancelled},
+		{"unknown", 0, 0, protocol.ErrInternal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := classifyError(tt.seErr, tt.lastErr); got != tt.want {
+				t.Errorf("classifyError(%d, %d) = %q, want %q", tt.seErr, tt.lastErr, got, tt.want)
+			}
+		})
+	}
+}
@@ -13,10 +13,12 @@ import (
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"syscall"
 	"unsafe"
 
 	"golang.org/x/sys/windows"
 
+	"github.com/sverrirab/wsl-host-start/internal/cmdline"
 	"github.com/sverrirab/wsl-host-start/internal/protocol"
 )
 
@@ -57,6 +59,13 @@ const (
 	swMaximize   = 3
 )
 
+// Resolve returns the executable that Execute and ExecuteConsole start for
+// file: bare command names are searched for in PATH using PATHEXT, and
+// anything else, or a name that is not found, is returned unchanged.
+func Resolve(file string) string {
+	return resolveCommand(file)
+}
+
 // resolveCommand searches for a bare command name in PATH using PATHEXT
 // extensions, mimicking how cmd.exe resolves commands for "start".
 func resolveCommand(name string) string {
@@ -129,8 +138,11 @@ func Execute(req *protocol.LaunchRequest) *protocol.LaunchResponse {
 	filePtr, _ := windows.UTF16PtrFromString(file)
 	sei.lpFile = filePtr
 
-	if len(req.Args) > 0 {
-		params := strings.Join(req.Args, " ")
+	params := req.CmdLine
+	if params == "" {
+		params = cmdline.Join(req.Args)
+	}
+	if params != "" {
 		paramsPtr, _ := windows.UTF16PtrFromString(params)
 		sei.lpParameters = paramsPtr
 	}
@@ -140,10 +152,39 @@ func Execute(req *protocol.LaunchRequest) *protocol.LaunchResponse {
 		sei.lpDirectory = dirPtr
 	}
 
+	// ShellExecuteEx has no environment parameter: the started process
+	// inherits the helper's, so forwarded vars are applied around the call.
+	if len(req.EnvVars) > 0 {
+		restore, err := setEnv(req.EnvVars)
+		if err != nil {
+			resp.Error = fmt.Sprintf("applying environment: %v", err)
+			resp.Category = protocol.ErrInternal
+			return resp
+		}
+		defer restore()
+	}
+
 	ret, _, err := procShellExecuteExW.Call(uintptr(unsafe.Pointer(&sei)))
 	if ret == 0 {
+		var lastErr int
+		var errno windows.Errno
+		if errors.As(err, &errno) {
+			lastErr = int(errno)
+		}
 		resp.Error = fmt.Sprintf("ShellExecuteEx failed: %v", err)
 		resp.ErrCode = int(sei.hInstApp)
+		resp.Category = classifyError(int(sei.hInstApp), lastErr)
+		if resp.Category == protocol.ErrFileNotFound {
+			resp.Path = file
+		}
+		return resp
+	}
+
+	if sei.hProcess == 0 && req.Wait {
+		// The target was handed to an already running application (DDE,
+		// single-instance apps, URLs), so there is no process to wait on.
+		resp.Error = "cannot wait: no process was started (the target was opened by an existing process)"
+		resp.Category = protocol.ErrNotWaitable
 		return resp
 	}
 
@@ -152,6 +193,7 @@ func Execute(req *protocol.LaunchRequest) *protocol.LaunchResponse {
 		if req.Wait {
 			if _, err := windows.WaitForSingleObject(sei.hProcess, windows.INFINITE); err != nil {
 				resp.Error = fmt.Sprintf("WaitForSingleObject failed: %v", err)
+				resp.Category = protocol.ErrInternal
 			} else {
 				var exitCode uint32
 				if err := windows.GetExitCodeProcess(sei.hProcess, &exitCode); err == nil {
@@ -172,6 +214,10 @@ func ExecuteConsole(req *protocol.LaunchRequest, stdin io.Reader) (int, error) {
 	file := resolveCommand(req.File)
 
 	cmd := exec.Command(file, req.Args...)
+	if req.CmdLine != "" {
+		// CmdLine includes the program name; Args are then ignored.
+		cmd.SysProcAttr = &syscall.SysProcAttr{CmdLine: cmdline.Quote(file) + " " + req.CmdLine}
+	}
 	cmd.Dir = req.WorkDir
 	cmd.Stdin = stdin
 	cmd.Stdout = os.Stdout
@@ -179,10 +225,7 @@ func ExecuteConsole(req *protocol.LaunchRequest, stdin io.Reader) (int, error) {
 
 	// Apply forwarded env vars on top of current environment.
 	if len(req.EnvVars) > 0 {
-		cmd.Env = os.Environ()
-		for k, v := range req.EnvVars {
-			cmd.Env = append(cmd.Env, k+"="+v)
-		}
+		cmd.Env = mergeEnv(os.Environ(), req.EnvVars)
 	}
 
 	if err := cmd.Run(); err != nil {
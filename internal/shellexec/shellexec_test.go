@@ -9,6 +9,8 @@ package shellexec_test
 // headless CI environments (GitHub Actions windows-latest).
 
 import (
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -78,6 +80,22 @@ func TestExecuteConsoleEmptyStdin(t *testing.T) {
 	}
 }
 
+// TestExecuteConsoleCmdLine passes a raw command line instead of Args.
+func TestExecuteConsoleCmdLine(t *testing.T) {
+	req := &protocol.LaunchRequest{
+		File:    "cmd.exe",
+		Args:    []string{"ignored"},
+		CmdLine: "/c exit 5",
+	}
+	code, err := shellexec.ExecuteConsole(req, strings.NewReader(""))
+	if err != nil {
+		t.Fatalf("ExecuteConsole: %v", err)
+	}
+	if code != 5 {
+		t.Errorf("exit code = %d, want 5", code)
+	}
+}
+
 // --- Execute tests (ShellExecuteExW, GUI shell operations) ---
 
 // TestExecuteWaitSuccess opens cmd.exe hidden and waits; verifies exit code 0.
@@ -117,6 +135,48 @@ func TestExecuteWaitExitCode(t *testing.T) {
 	}
 }
 
+// TestExecuteWaitQuotedArgs checks that an argument with spaces reaches
+// the program as one argument: findstr exits 0 only if it searches for the
+// whole phrase in a file that contains it.
+func TestExecuteWaitQuotedArgs(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "with space.txt")
+	if err := os.WriteFile(file, []byte("hello from wsl\r\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	req := &protocol.LaunchRequest{
+		File: "findstr.exe",
+		Args: []string{"/c:hello from", file},
+		Verb: "open",
+		Show: protocol.ShowHidden,
+		Wait: true,
+	}
+	resp := shellexec.Execute(req)
+	if resp.Error != "" {
+		t.Fatalf("Execute: %s (errcode %d)", resp.Error, resp.ErrCode)
+	}
+	if resp.ExitCode != 0 {
+		t.Errorf("ExitCode = %d, want 0 (arguments split or mangled)", resp.ExitCode)
+	}
+}
+
+// TestExecuteWaitCmdLine passes a raw command line to ShellExecuteEx.
+func TestExecuteWaitCmdLine(t *testing.T) {
+	req := &protocol.LaunchRequest{
+		File:    "cmd.exe",
+		CmdLine: "/c exit 9",
+		Verb:    "open",
+		Show:    protocol.ShowHidden,
+		Wait:    true,
+	}
+	resp := shellexec.Execute(req)
+	if resp.Error != "" {
+		t.Fatalf("Execute: %s (errcode %d)", resp.Error, resp.ErrCode)
+	}
+	if resp.ExitCode != 9 {
+		t.Errorf("ExitCode = %d, want 9", resp.ExitCode)
+	}
+}
+
 // TestExecuteNoWait launches a process without waiting. We just verify no
 // error is returned and a process handle is reported.
 func TestExecuteNoWait(t *testing.T) {
@@ -75,8 +75,22 @@ func VerifyAllConfigs(dir string) ([]VerifyResult, error) {
 	return results, nil
 }
 
-// VerifyOrErr checks all config files and returns an error if any existing
-// file has an invalid or missing signature.
+// SignatureError reports a config file whose signature is missing or invalid.
+type SignatureError struct {
+	Path string
+	Err  error
+}
+
+func (e *SignatureError) Error() string {
+	return fmt.Sprintf("config signature check failed: %v\nRun wstart-host.exe --sign-config after making legitimate edits", e.Err)
+}
+
+func (e *SignatureError) Unwrap() error {
+	return e.Err
+}
+
+// VerifyOrErr checks all config files and returns a *SignatureError if any
+// existing file has an invalid or missing signature.
 func VerifyOrErr(dir string) error {
 	results, err := VerifyAllConfigs(dir)
 	if err != nil {
@@ -84,7 +98,7 @@ func VerifyOrErr(dir string) error {
 	}
 	for _, r := range results {
 		if r.Exists && r.SigErr != nil {
-			return fmt.Errorf("config signature check failed: %w\nRun wstart-host.exe --sign-config after making legitimate edits", r.SigErr)
+			return &SignatureError{Path: r.Path, Err: r.SigErr}
 		}
 	}
 	return nil
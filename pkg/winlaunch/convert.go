@@ -0,0 +1,264 @@
+package winlaunch
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/sverrirab/wsl-host-start/internal/launch"
+	"github.com/sverrirab/wsl-host-start/internal/protocol"
+)
+
+// This file converts between the public types and the internal ones the
+// launch pipeline and the wire protocol use.
+
+func toLaunchOptions(o *Options) *launch.Options {
+	opts := &launch.Options{
+		Target:    o.Target,
+		Args:      o.Args,
+		Verb:      o.Verb,
+		WorkDir:   o.WorkDir,
+		Show:      o.Show,
+		Wait:      o.Wait,
+		DryRun:    o.DryRun,
+		Verbose:   o.Verbose,
+		RawArgs:   o.RawArgs,
+		CmdLine:   o.CmdLine,
+		Env:       o.Env,
+		Ext:       o.Ext,
+		ConfigDir: o.ConfigDir,
+		Stdin:     o.Stdin,
+		Stdout:    o.Stdout,
+		Stderr:    o.Stderr,
+	}
+	switch t := o.Transport.(type) {
+	case nil:
+	case *helperTransport:
+		opts.Transport = t.t
+	default:
+		opts.Transport = &publicTransport{t: t}
+	}
+	return opts
+}
+
+// publicTransport lets the pipeline use a caller's Transport.
+type publicTransport struct {
+	t Transport
+}
+
+func (p *publicTransport) Request(ctx context.Context, req *protocol.Request) (*protocol.Response, error) {
+	resp, err := p.t.Request(ctx, fromProtocolRequest(req))
+	if err != nil {
+		return nil, err
+	}
+	return toProtocolResponse(resp), nil
+}
+
+func (p *publicTransport) Exec(ctx context.Context, req *protocol.LaunchRequest, stdin io.Reader, stdout, stderr io.Writer) (int, error) {
+	return p.t.Exec(ctx, fromLaunchRequest(req), stdin, stdout, stderr)
+}
+
+// helperTransport exposes the pipeline's helper transport as a Transport.
+type helperTransport struct {
+	t launch.Transport
+}
+
+func (h *helperTransport) Request(ctx context.Context, req *Request) (*Response, error) {
+	resp, err := h.t.Request(ctx, toProtocolRequest(req))
+	if err != nil {
+		return nil, err
+	}
+	return fromProtocolResponse(resp), nil
+}
+
+func (h *helperTransport) Exec(ctx context.Context, req *LaunchRequest, stdin io.Reader, stdout, stderr io.Writer) (int, error) {
+	return h.t.Exec(ctx, toLaunchRequest(req), stdin, stdout, stderr)
+}
+
+func toProtocolRequest(r *Request) *protocol.Request {
+	if r == nil {
+		return nil
+	}
+	req := &protocol.Request{Require: r.Require, Hello: r.Hello, Drives: r.Drives, Launch: toLaunchRequest(r.Launch)}
+	for i := range r.Launches {
+		req.Launches = append(req.Launches, *toLaunchRequest(&r.Launches[i]))
+	}
+	return req
+}
+
+func fromProtocolRequest(r *protocol.Request) *Request {
+	if r == nil {
+		return nil
+	}
+	req := &Request{Require: r.Require, Hello: r.Hello, Drives: r.Drives, Launch: fromLaunchRequest(r.Launch)}
+	for i := range r.Launches {
+		req.Launches = append(req.Launches, *fromLaunchRequest(&r.Launches[i]))
+	}
+	return req
+}
+
+func toProtocolResponse(r *Response) *protocol.Response {
+	if r == nil {
+		return nil
+	}
+	resp := &protocol.Response{
+		Hello:  toHelloResponse(r.Hello),
+		Drives: toDrivesResponse(r.Drives),
+		Launch: toLaunchResponse(r.Launch),
+		Error:  r.Error,
+	}
+	for i := range r.Launches {
+		resp.Launches = append(resp.Launches, *toLaunchResponse(&r.Launches[i]))
+	}
+	return resp
+}
+
+func fromProtocolResponse(r *protocol.Response) *Response {
+	if r == nil {
+		return nil
+	}
+	resp := &Response{
+		Hello:  fromHelloResponse(r.Hello),
+		Drives: fromDrivesResponse(r.Drives),
+		Launch: fromLaunchResponse(r.Launch),
+		Error:  r.Error,
+	}
+	for i := range r.Launches {
+		resp.Launches = append(resp.Launches, *fromLaunchResponse(&r.Launches[i]))
+	}
+	return resp
+}
+
+func toLaunchRequest(r *LaunchRequest) *protocol.LaunchRequest {
+	if r == nil {
+		return nil
+	}
+	return &protocol.LaunchRequest{
+		File:           r.File,
+		Verb:           r.Verb,
+		Args:           r.Args,
+		WorkDir:        r.WorkDir,
+		Show:           r.Show,
+		Wait:           r.Wait,
+		EnvVars:        r.EnvVars,
+		ResolveAliases: r.ResolveAliases,
+		CmdLine:        r.CmdLine,
+		AdHocEnv:       r.AdHocEnv,
+	}
+}
+
+func fromLaunchRequest(r *protocol.LaunchRequest) *LaunchRequest {
+	if r == nil {
+		return nil
+	}
+	return &LaunchRequest{
+		File:           r.File,
+		Verb:           r.Verb,
+		Args:           r.Args,
+		WorkDir:        r.WorkDir,
+		Show:           r.Show,
+		Wait:           r.Wait,
+		EnvVars:        r.EnvVars,
+		ResolveAliases: r.ResolveAliases,
+		CmdLine:        r.CmdLine,
+		AdHocEnv:       r.AdHocEnv,
+	}
+}
+
+func toLaunchResponse(r *LaunchResponse) *protocol.LaunchResponse {
+	if r == nil {
+		return nil
+	}
+	return &protocol.LaunchResponse{
+		ExitCode:   r.ExitCode,
+		PID:        r.PID,
+		Error:      r.Error,
+		ErrCode:    r.ErrCode,
+		Category:   r.Category,
+		Rule:       r.Rule,
+		Path:       r.Path,
+		DroppedEnv: r.DroppedEnv,
+	}
+}
+
+func fromLaunchResponse(r *protocol.LaunchResponse) *LaunchResponse {
+	if r == nil {
+		return nil
+	}
+	return &LaunchResponse{
+		ExitCode:   r.ExitCode,
+		PID:        r.PID,
+		Error:      r.Error,
+		ErrCode:    r.ErrCode,
+		Category:   r.Category,
+		Rule:       r.Rule,
+		Path:       r.Path,
+		DroppedEnv: r.DroppedEnv,
+	}
+}
+
+func toHelloResponse(r *HelloResponse) *protocol.HelloResponse {
+	if r == nil {
+		return nil
+	}
+	return &protocol.HelloResponse{ProtocolVersion: r.ProtocolVersion, Version: r.Version, Capabilities: r.Capabilities}
+}
+
+func fromHelloResponse(r *protocol.HelloResponse) *HelloResponse {
+	if r == nil {
+		return nil
+	}
+	return &HelloResponse{ProtocolVersion: r.ProtocolVersion, Version: r.Version, Capabilities: r.Capabilities}
+}
+
+func toDrivesResponse(r *DrivesResponse) *protocol.DrivesResponse {
+	if r == nil {
+		return nil
+	}
+	return &protocol.DrivesResponse{Drives: toDriveInfos(r.Drives), Username: r.Username, LocalAppData: r.LocalAppData}
+}
+
+func fromDrivesResponse(r *protocol.DrivesResponse) *DrivesResponse {
+	if r == nil {
+		return nil
+	}
+	resp := &DrivesResponse{Username: r.Username, LocalAppData: r.LocalAppData}
+	for _, d := range r.Drives {
+		resp.Drives = append(resp.Drives, DriveInfo{Letter: d.Letter, Type: d.Type, Target: d.Target, Label: d.Label})
+	}
+	return resp
+}
+
+func toDriveInfos(drives []DriveInfo) []protocol.DriveInfo {
+	var out []protocol.DriveInfo
+	for _, d := range drives {
+		out = append(out, protocol.DriveInfo{Letter: d.Letter, Type: d.Type, Target: d.Target, Label: d.Label})
+	}
+	return out
+}
+
+// helperError is an error from the pipeline with its *launch.HelperError
+// also available as a *HelperError. The message is unchanged.
+type helperError struct {
+	he  *HelperError
+	err error
+}
+
+func (e *helperError) Error() string   { return e.err.Error() }
+func (e *helperError) Unwrap() []error { return []error{e.he, e.err} }
+
+// fromError exposes the helper failure in err, if any, as a *HelperError.
+func fromError(err error) error {
+	var he *launch.HelperError
+	if !errors.As(err, &he) {
+		return err
+	}
+	return &helperError{
+		he:  &HelperError{Category: he.Category, Message: he.Message, Code: he.Code, Rule: he.Rule, Path: he.Path},
+		err: err,
+	}
+}
+
+func toHelperError(e *HelperError) *launch.HelperError {
+	return &launch.HelperError{Category: e.Category, Message: e.Message, Code: e.Code, Rule: e.Rule, Path: e.Path}
+}
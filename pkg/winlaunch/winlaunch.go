@@ -0,0 +1,331 @@
+// Package winlaunch opens Windows files, URLs and programs from Go code
+// running in WSL. It is the library behind the wstart CLI: it translates
+// WSL paths to Windows paths, locates the wstart-host.exe helper, reads its
+// drive table, and launches or runs targets through it.
+//
+// The types are defined here rather than shared with the CLI's internals,
+// so the API only changes when this package does.
+//
+//	res, err := winlaunch.Launch(ctx, &winlaunch.Options{Target: "report.xlsx"})
+//	if errors.Is(err, winlaunch.ErrPolicyDenied) { ... }
+package winlaunch
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/sverrirab/wsl-host-start/internal/drivecache"
+	"github.com/sverrirab/wsl-host-start/internal/launch"
+	"github.com/sverrirab/wsl-host-start/internal/pathconv"
+	"github.com/sverrirab/wsl-host-start/internal/protocol"
+)
+
+// Options configures a launch. Target is required; everything else falls
+// back to config.toml and then to the CLI's defaults.
+type Options struct {
+	// Target is the file, directory, URL or program to open.
+	Target string
+	// Args are passed to the program, with WSL paths in them translated
+	// unless RawArgs is set.
+	Args []string
+	// Verb is the ShellExecuteEx verb, e.g. "open", "edit" or "runas".
+	Verb    string
+	WorkDir string
+	// Show is one of the Show* window states.
+	Show string
+	// Wait waits for the program to exit and reports its exit code.
+	Wait bool
+	// DryRun prints the request to Stdout instead of launching it.
+	DryRun  bool
+	Verbose bool
+	RawArgs bool
+
+	// CmdLine, if set, is passed to the program verbatim instead of Args.
+	CmdLine string
+
+	// Env holds variables set for this launch only. They are forwarded
+	// whether or not the forward list names them, unless they are blocked.
+	Env map[string]string
+
+	// Ext is the extension of the temp file written when Target is "-"
+	// (default ".txt").
+	Ext string
+
+	// Transport, if set, replaces the spawned wstart-host.exe. Helper
+	// discovery and the on-disk caches are skipped, and config is read
+	// from ConfigDir (defaults only if empty).
+	Transport Transport
+	ConfigDir string
+
+	// Stdin, Stdout and Stderr are wired to the program in Wait mode and
+	// default to the process's own.
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// Result is the outcome of a launch.
+type Result struct {
+	// ExitCode is the program's exit code when Options.Wait is set.
+	ExitCode int
+	// PID is the launched process, when the helper reports one.
+	PID int
+}
+
+// HelperError is a launch failure reported by the helper. Errors returned
+// by Launch and Exec match it with errors.As when the helper reported one.
+type HelperError struct {
+	// Category classifies the failure, e.g. "denied-by-allowlist" or
+	// "file-not-found"; empty for helpers that predate categories.
+	Category string
+	Message  string
+	// Code is the raw Windows code when available.
+	Code int
+	// Rule is the offending deny list entry or allowlist rule, if any.
+	Rule string
+	// Path is the offending file, if any.
+	Path string
+}
+
+func (e *HelperError) Error() string {
+	return toHelperError(e).Error()
+}
+
+// Is reports whether e is a policy denial, so that
+// errors.Is(err, ErrPolicyDenied) matches it.
+func (e *HelperError) Is(target error) bool {
+	return toHelperError(e).Is(target)
+}
+
+// Hint returns guidance for fixing the error, or "" if there is none.
+func (e *HelperError) Hint() string {
+	return toHelperError(e).Hint()
+}
+
+// Transport carries requests to the helper. Set Options.Transport to
+// substitute a fake in tests.
+type Transport interface {
+	// Request sends a combined request and returns the combined response.
+	// A request with only Hello set performs the protocol handshake.
+	Request(ctx context.Context, req *Request) (*Response, error)
+
+	// Exec runs req with stdio passthrough and returns the child's exit code.
+	Exec(ctx context.Context, req *LaunchRequest, stdin io.Reader, stdout, stderr io.Writer) (int, error)
+}
+
+// Request is one exchange with the helper. Any combination of sections
+// may be set; the helper answers all of them in one Response.
+type Request struct {
+	// Require lists capabilities the request depends on.
+	Require []string
+	Hello   bool
+	Drives  bool
+	Launch  *LaunchRequest
+	// Launches are run in order after Launch; each gets its own result.
+	Launches []LaunchRequest
+}
+
+// Response answers a Request.
+type Response struct {
+	Hello  *HelloResponse
+	Drives *DrivesResponse
+	Launch *LaunchResponse
+	// Launches holds one result per Request.Launches entry, in order.
+	Launches []LaunchResponse
+	// Error is set when the request as a whole could not be served.
+	Error string
+}
+
+// LaunchRequest describes one launch on the Windows side.
+type LaunchRequest struct {
+	File    string
+	Verb    string
+	Args    []string
+	WorkDir string
+	Show    string
+	Wait    bool
+	EnvVars map[string]string
+
+	// ResolveAliases asks the helper to apply drive aliases to File and
+	// WorkDir itself.
+	ResolveAliases bool
+
+	// CmdLine, if set, replaces Args and is passed to the program as is.
+	CmdLine string
+
+	// AdHocEnv names the EnvVars given for this launch only.
+	AdHocEnv []string
+}
+
+// LaunchResponse is the helper's result for one LaunchRequest.
+type LaunchResponse struct {
+	ExitCode int
+	PID      int
+	Error    string
+	// ErrCode is the raw Windows code when available.
+	ErrCode int
+	// Category classifies Error, e.g. "denied-by-allowlist".
+	Category string
+	// Rule is the offending deny list entry or allowlist rule, if any.
+	Rule string
+	// Path is the offending file, if any.
+	Path string
+	// DroppedEnv lists the EnvVars the helper removed under its own policy.
+	DroppedEnv []string
+}
+
+// HelloResponse is the helper's answer to the handshake.
+type HelloResponse struct {
+	ProtocolVersion int
+	Version         string
+	Capabilities    []string
+}
+
+// DrivesResponse is the host's drive table.
+type DrivesResponse struct {
+	Drives       []DriveInfo
+	Username     string
+	LocalAppData string
+}
+
+// DriveInfo describes a single Windows drive letter.
+type DriveInfo struct {
+	Letter string
+	// Type is "fixed", "network", "subst", "removable", "cdrom",
+	// "ramdisk" or "unknown".
+	Type   string
+	Target string
+	Label  string
+}
+
+// Window states for Options.Show.
+const (
+	ShowNormal = protocol.ShowNormal
+	ShowMin    = protocol.ShowMin
+	ShowMax    = protocol.ShowMax
+	ShowHidden = protocol.ShowHidden
+)
+
+// Exit codes reserved for wstart's own failures (see ExitCode).
+const (
+	ExitInternal = protocol.ExitInternal
+	ExitDenied   = protocol.ExitDenied
+	ExitNotFound = protocol.ExitNotFound
+)
+
+// Sentinel errors for use with errors.Is.
+var (
+	ErrHelperNotFound  = launch.ErrHelperNotFound
+	ErrVersionMismatch = launch.ErrVersionMismatch
+	ErrPolicyDenied    = launch.ErrPolicyDenied
+	ErrInterop         = launch.ErrInterop
+)
+
+// Launch opens opts.Target on the Windows host via ShellExecuteEx. With
+// opts.Wait and the "open" verb, a program instead runs with its stdio
+// connected to opts.Stdin, opts.Stdout and opts.Stderr (see Exec).
+func Launch(ctx context.Context, opts *Options) (*Result, error) {
+	res, err := launch.RunContext(ctx, toLaunchOptions(opts))
+	if err != nil {
+		return nil, fromError(err)
+	}
+	return &Result{ExitCode: res.ExitCode, PID: res.PID}, nil
+}
+
+// Exec runs opts.Target, waits for it to exit and returns its exit code in
+// Result.ExitCode. opts.Wait is implied. With the "open" verb (the
+// default) the program's stdio is connected to opts.Stdin, opts.Stdout and
+// opts.Stderr; other verbs, and documents written from stdin, go through
+// ShellExecuteEx without stdio passthrough.
+func Exec(ctx context.Context, opts *Options) (*Result, error) {
+	o := *opts
+	o.Wait = true
+	return Launch(ctx, &o)
+}
+
+// FindHelper returns the WSL path of wstart-host.exe.
+func FindHelper(ctx context.Context) (string, error) {
+	return launch.FindHelper(ctx)
+}
+
+// HelperTransport returns the Transport that spawns the helper at
+// helperPath, as Launch does when Options.Transport is nil.
+func HelperTransport(helperPath string) Transport {
+	return &helperTransport{t: launch.HelperTransport(helperPath)}
+}
+
+// Drives returns the host's drive table from the local cache, asking the
+// helper at helperPath for a fresh one if the cache is stale or missing.
+func Drives(ctx context.Context, helperPath string) (*DrivesResponse, error) {
+	cache := drivecache.New(0)
+	if resp, err := cache.Cached(); err == nil {
+		return fromDrivesResponse(resp), nil
+	}
+	resp, err := cache.RefreshContext(ctx, helperPath)
+	if err != nil {
+		return nil, err
+	}
+	return fromDrivesResponse(resp), nil
+}
+
+// RefreshDrives asks the helper at helperPath for the drive table and
+// updates the local cache.
+func RefreshDrives(ctx context.Context, helperPath string) (*DrivesResponse, error) {
+	resp, err := drivecache.New(0).RefreshContext(ctx, helperPath)
+	if err != nil {
+		return nil, err
+	}
+	return fromDrivesResponse(resp), nil
+}
+
+// Converter translates paths between WSL and Windows, applying drive
+// aliases.
+type Converter struct {
+	conv *pathconv.Converter
+}
+
+// NewConverter returns a path converter that prefers the drive letters of
+// subst drives in drives and of configAliases (letter to target path) when
+// preferAliases is set.
+func NewConverter(drives []DriveInfo, configAliases map[string]string, preferAliases bool) *Converter {
+	return &Converter{conv: pathconv.NewConverter(toDriveInfos(drives), configAliases, preferAliases)}
+}
+
+// ToWindows translates a WSL path to a Windows path. URLs and bare command
+// names are returned unchanged.
+func (c *Converter) ToWindows(ctx context.Context, wslPath string) (string, error) {
+	return c.conv.ToWindowsContext(ctx, wslPath)
+}
+
+// ToWSL translates a Windows path to a WSL path, expanding drive aliases.
+func (c *Converter) ToWSL(ctx context.Context, winPath string) (string, error) {
+	return c.conv.ToWSLContext(ctx, winPath)
+}
+
+// ApplyAlias replaces the longest physical path prefix that has a drive
+// alias with the alias letter, e.g. C:\dev\ws\a becomes P:\a.
+func (c *Converter) ApplyAlias(winPath string) string {
+	return c.conv.ApplyAlias(winPath)
+}
+
+// ExpandAlias replaces an aliased drive letter with the path it stands
+// for; the reverse of ApplyAlias.
+func (c *Converter) ExpandAlias(winPath string) string {
+	return c.conv.ExpandAlias(winPath)
+}
+
+// ToWindows translates a WSL path to a Windows path without drive aliases.
+// URLs and bare command names are returned unchanged.
+func ToWindows(ctx context.Context, wslPath string) (string, error) {
+	return pathconv.NewConverter(nil, nil, false).ToWindowsContext(ctx, wslPath)
+}
+
+// ExitCode returns the exit code the wstart CLI would use for err.
+func ExitCode(err error) int {
+	var he *HelperError
+	if errors.As(err, &he) {
+		return launch.ExitCode(toHelperError(he))
+	}
+	return launch.ExitCode(err)
+}
@@ -0,0 +1,214 @@
+package winlaunch_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/sverrirab/wsl-host-start/pkg/winlaunch"
+)
+
+// fakeTransport records requests and answers them without a Windows host.
+type fakeTransport struct {
+	caps     []string
+	requests []*winlaunch.Request
+	launch   winlaunch.LaunchResponse
+	exitCode int
+}
+
+func (f *fakeTransport) Request(ctx context.Context, req *winlaunch.Request) (*winlaunch.Response, error) {
+	f.requests = append(f.requests, req)
+	resp := &winlaunch.Response{}
+	if req.Hello {
+		resp.Hello = &winlaunch.HelloResponse{ProtocolVersion: 3, Version: "fake", Capabilities: f.caps}
+	}
+	if req.Launch != nil {
+		l := f.launch
+		resp.Launch = &l
+	}
+	return resp, nil
+}
+
+func (f *fakeTransport) Exec(ctx context.Context, req *winlaunch.LaunchRequest, stdin io.Reader, stdout, stderr io.Writer) (int, error) {
+	f.requests = append(f.requests, &winlaunch.Request{Launch: req})
+	data, _ := io.ReadAll(stdin)
+	_, _ = stdout.Write(data)
+	return f.exitCode, nil
+}
+
+func TestLaunchWithFakeTransport(t *testing.T) {
+	fake := &fakeTransport{caps: []string{"launch", "request"}, launch: winlaunch.LaunchResponse{PID: 42}}
+
+	res, err := winlaunch.Launch(context.Background(), &winlaunch.Options{
+		Target:    "notepad.exe",
+		Args:      []string{"x"},
+		Transport: fake,
+	})
+	if err != nil {
+		t.Fatalf("Launch: %v", err)
+	}
+	if res.PID != 42 {
+		t.Errorf("PID = %d, want 42", res.PID)
+	}
+	if len(fake.requests) != 2 {
+		t.Fatalf("got %d requests, want handshake + launch", len(fake.requests))
+	}
+	got := fake.requests[1].Launch
+	if got == nil || got.File != "notepad.exe" || got.Verb != "open" || len(got.Args) != 1 {
+		t.Errorf("launch request = %+v", got)
+	}
+}
+
+func TestExecWithFakeTransport(t *testing.T) {
+	fake := &fakeTransport{caps: []string{"exec"}, exitCode: 3}
+	var stdout bytes.Buffer
+
+	res, err := winlaunch.Exec(context.Background(), &winlaunch.Options{
+		Target:    "p4",
+		Transport: fake,
+		Stdin:     strings.NewReader("input"),
+		Stdout:    &stdout,
+	})
+	if err != nil {
+		t.Fatalf("Exec: %v", err)
+	}
+	if res.ExitCode != 3 {
+		t.Errorf("ExitCode = %d, want 3", res.ExitCode)
+	}
+	if stdout.String() != "input" {
+		t.Errorf("stdout = %q, want stdin passed through", stdout.String())
+	}
+}
+
+func TestLaunchErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		fake     *fakeTransport
+		sentinel error
+		code     int
+	}{
+		{
+			name:     "denied",
+			fake:     &fakeTransport{caps: []string{"launch", "request"}, launch: winlaunch.LaunchResponse{Error: "denied", Category: "denied-by-allowlist"}},
+			sentinel: winlaunch.ErrPolicyDenied,
+			code:     winlaunch.ExitDenied,
+		},
+		{
+			name:     "old helper",
+			fake:     &fakeTransport{caps: []string{"exec"}},
+			sentinel: winlaunch.ErrVersionMismatch,
+			code:     winlaunch.ExitInternal,
+		},
+	}
+	for _, tt := range tests {
+		_, err := winlaunch.Launch(context.Background(), &winlaunch.Options{Target: "p4", Transport: tt.fake})
+		if !errors.Is(err, tt.sentinel) {
+			t.Errorf("%s: err = %v, want %v", tt.name, err, tt.sentinel)
+		}
+		if got := winlaunch.ExitCode(err); got != tt.code {
+			t.Errorf("%s: ExitCode = %d, want %d", tt.name, got, tt.code)
+		}
+	}
+}
+
+func TestLaunchHelperError(t *testing.T) {
+	fake := &fakeTransport{caps: []string{"launch", "request"}, launch: winlaunch.LaunchResponse{
+		Error: "denied", ErrCode: 5, Category: "denied-by-allowlist", Rule: "p4 [sync]", Path: "allowlist.toml",
+	}}
+	_, err := winlaunch.Launch(context.Background(), &winlaunch.Options{Target: "p4", Transport: fake})
+
+	var he *winlaunch.HelperError
+	if !errors.As(err, &he) {
+		t.Fatalf("err = %T %v, want a *winlaunch.HelperError", err, err)
+	}
+	want := winlaunch.HelperError{Category: "denied-by-allowlist", Message: "denied", Code: 5, Rule: "p4 [sync]", Path: "allowlist.toml"}
+	if *he != want {
+		t.Errorf("HelperError = %+v, want %+v", *he, want)
+	}
+	if !errors.Is(he, winlaunch.ErrPolicyDenied) || !strings.Contains(he.Hint(), "p4 [sync]") {
+		t.Errorf("HelperError %v: not a policy denial, or hint %q lacks the rule", he, he.Hint())
+	}
+	if got := winlaunch.ExitCode(he); got != winlaunch.ExitDenied {
+		t.Errorf("ExitCode(HelperError) = %d, want %d", got, winlaunch.ExitDenied)
+	}
+}
+
+func TestLaunchCmdLine(t *testing.T) {
+	fake := &fakeTransport{caps: []string{"launch", "request", "cmdLine"}}
+	_, err := winlaunch.Launch(context.Background(), &winlaunch.Options{
+		Target:    "cmd.exe",
+		CmdLine:   `/c echo "a  b"`,
+		Transport: fake,
+	})
+	if err != nil {
+		t.Fatalf("Launch: %v", err)
+	}
+	if got := fake.requests[len(fake.requests)-1].Launch; got == nil || got.CmdLine != `/c echo "a  b"` {
+		t.Errorf("launch request = %+v", got)
+	}
+
+	old := &fakeTransport{caps: []string{"launch", "request"}}
+	_, err = winlaunch.Launch(context.Background(), &winlaunch.Options{Target: "cmd.exe", CmdLine: "/c ver", Transport: old})
+	if !errors.Is(err, winlaunch.ErrVersionMismatch) {
+		t.Errorf("helper without cmdLine: err = %v, want ErrVersionMismatch", err)
+	}
+
+	_, err = winlaunch.Launch(context.Background(), &winlaunch.Options{
+		Target:    "cmd.exe",
+		Args:      []string{"/c"},
+		CmdLine:   "/c ver",
+		Transport: fake,
+	})
+	if err == nil {
+		t.Error("CmdLine with Args: expected an error")
+	}
+}
+
+func TestLaunchAdHocEnv(t *testing.T) {
+	fake := &fakeTransport{caps: []string{"launch", "request", "envVars", "adHocEnv"}}
+
+	_, err := winlaunch.Launch(context.Background(), &winlaunch.Options{
+		Target:    "p4v",
+		Env:       map[string]string{"P4CLIENT": "ws2"},
+		Transport: fake,
+	})
+	if err != nil {
+		t.Fatalf("Launch: %v", err)
+	}
+	got := fake.requests[len(fake.requests)-1].Launch
+	if got.EnvVars["P4CLIENT"] != "ws2" || len(got.AdHocEnv) != 1 || got.AdHocEnv[0] != "P4CLIENT" {
+		t.Errorf("launch request env = %v, adHocEnv = %v", got.EnvVars, got.AdHocEnv)
+	}
+
+	_, err = winlaunch.Launch(context.Background(), &winlaunch.Options{
+		Target:    "p4v",
+		Env:       map[string]string{"P4PASSWD": "secret"},
+		Transport: fake,
+	})
+	if !errors.Is(err, winlaunch.ErrPolicyDenied) {
+		t.Errorf("Launch with blocked -env: error = %v, want ErrPolicyDenied", err)
+	}
+}
+
+func TestDryRunRedactsSecrets(t *testing.T) {
+	fake := &fakeTransport{caps: []string{"launch", "request"}}
+	var stdout bytes.Buffer
+
+	_, err := winlaunch.Launch(context.Background(), &winlaunch.Options{
+		Target:    "code",
+		Env:       map[string]string{"GITHUB_TOKEN": "ghp_secret", "DEBUG": "1"},
+		DryRun:    true,
+		Transport: fake,
+		Stdout:    &stdout,
+	})
+	if err != nil {
+		t.Fatalf("Launch: %v", err)
+	}
+	out := stdout.String()
+	if strings.Contains(out, "ghp_secret") || !strings.Contains(out, `"GITHUB_TOKEN": "***"`) || !strings.Contains(out, `"DEBUG": "1"`) {
+		t.Errorf("dry-run output does not redact the token:\n%s", out)
+	}
+}